@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// NewMemoryBackend returns a Backend that stores its entries in c.
+func NewMemoryBackend[K comparable, V any](c *Cache[K, V]) Backend[K, V] {
+	return memoryBackend[K, V]{c}
+}
+
+type memoryBackend[K comparable, V any] struct {
+	c *Cache[K, V]
+}
+
+func (b memoryBackend[K, V]) Get(_ context.Context, k K) (V, time.Duration, bool, error) {
+	v, ttl, ok := b.c.getWithTTL(k)
+	return v, ttl, ok, nil
+}
+
+func (b memoryBackend[K, V]) Set(_ context.Context, k K, v V, ttl time.Duration) error {
+	b.c.SetWithTTL(k, v, ttl)
+	return nil
+}
+
+func (b memoryBackend[K, V]) Delete(_ context.Context, k K) error {
+	b.c.Delete(k)
+	return nil
+}
+
+// NewFileBackend returns a FileBackend storing its entries in dir, which is
+// created if it does not exist. The keys and values are encoded with codec.
+//
+// The files are named after the hash of a deterministic encoding of their key,
+// which covers all of its fields, exported or not, and, unlike the encoding of
+// some codecs such as GobCodec, does not change between runs of the program.
+// The only exception are pointers and channels, which are encoded by address,
+// so keys holding them are never found again once decoded.
+//
+// The backend can be further configured with opts.
+func NewFileBackend[K comparable, V any](dir string, codec Codec, opts ...FileBackendOption[K, V]) (*FileBackend[K, V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: failed to create backend dir: %w", err)
+	}
+
+	b := &FileBackend[K, V]{dir: dir, codec: codec, clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b, nil
+}
+
+// FileBackendOption configures a FileBackend created by NewFileBackend.
+type FileBackendOption[K comparable, V any] func(*FileBackend[K, V])
+
+// WithBackendClock makes the backend tell the time with clk instead of the
+// system clock, which is mostly useful to test expiry with a fake clock.
+func WithBackendClock[K comparable, V any](clk clock.Clock) FileBackendOption[K, V] {
+	return func(b *FileBackend[K, V]) {
+		b.clock = clk
+	}
+}
+
+// FileBackend is a Backend that stores each entry in its own file, named
+// after the hash of its key, in a directory of the local disk.
+//
+// Expired entries are deleted when they are read or when Prune is called. Only
+// the entries that are still expired once moved out of the way are deleted,
+// so an entry set concurrently is never lost.
+type FileBackend[K comparable, V any] struct {
+	dir   string
+	codec Codec
+	clock clock.Clock
+}
+
+// Get implements Backend.
+func (b *FileBackend[K, V]) Get(_ context.Context, k K) (V, time.Duration, bool, error) {
+	var zero V
+	path := b.path(k)
+	rec, err := b.read(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return zero, 0, false, nil
+		}
+		return zero, 0, false, err
+	}
+
+	now := b.now()
+	if rec.expired(now) {
+		return zero, 0, false, b.removeExpired(path)
+	}
+
+	if rec.Key != k {
+		// Hash collision.
+		return zero, 0, false, nil
+	}
+
+	var ttl time.Duration
+	if rec.Expires != 0 {
+		ttl = max(time.Duration(rec.Expires-now), 1)
+	}
+	return rec.Value, ttl, true, nil
+}
+
+// Set implements Backend.
+//
+// The entry is written to a temporary file that is then renamed, so readers
+// never see a partially written entry.
+func (b *FileBackend[K, V]) Set(_ context.Context, k K, v V, ttl time.Duration) error {
+	path := b.path(k)
+	rec := record[K, V]{Key: k, Value: v}
+	if ttl > 0 {
+		rec.Expires = b.now() + int64(ttl)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := b.codec.NewEncoder(buf).Encode(&rec); err != nil {
+		return fmt.Errorf("cache: failed to encode entry: %w", err)
+	}
+
+	f, err := b.createTemp()
+	if err != nil {
+		return fmt.Errorf("cache: failed to create entry file: %w", err)
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("cache: failed to write entry file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("cache: failed to write entry file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("cache: failed to write entry file: %w", err)
+	}
+
+	return nil
+}
+
+// Delete implements Backend.
+func (b *FileBackend[K, V]) Delete(_ context.Context, k K) error {
+	if err := os.Remove(b.path(k)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cache: failed to delete entry: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes the files of all expired entries.
+func (b *FileBackend[K, V]) Prune() error {
+	files, err := filepath.Glob(filepath.Join(b.dir, "*.entry"))
+	if err != nil {
+		return fmt.Errorf("cache: failed to list entries: %w", err)
+	}
+
+	now := b.now()
+	for _, path := range files {
+		rec, err := b.read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if rec.expired(now) {
+			if err := b.removeExpired(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeExpired deletes the file at path if its entry is expired.
+//
+// The file is first renamed, so that an entry set concurrently can not be
+// replaced while it is checked. If the renamed file holds an entry that did not
+// expire, it is linked back to path, unless another entry was set there in the
+// meantime.
+func (b *FileBackend[K, V]) removeExpired(path string) error {
+	f, err := b.createTemp()
+	if err != nil {
+		return fmt.Errorf("cache: failed to delete expired entry: %w", err)
+	}
+	f.Close()
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if err := os.Rename(path, tmp); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("cache: failed to delete expired entry: %w", err)
+	}
+
+	if rec, err := b.read(tmp); err == nil && !rec.expired(b.now()) {
+		if err := os.Link(tmp, path); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("cache: failed to restore entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// createTemp creates a new temporary file in the directory of the backend,
+// which is not listed by Prune.
+func (b *FileBackend[K, V]) createTemp() (*os.File, error) {
+	return os.CreateTemp(b.dir, ".tmp-*")
+}
+
+// path returns the path of the file of k.
+func (b *FileBackend[K, V]) path(k K) string {
+	var buf [64]byte
+	sum := sha256.Sum256(appendKey(buf[:0], reflect.ValueOf(&k).Elem()))
+	return filepath.Join(b.dir, hex.EncodeToString(sum[:])+".entry")
+}
+
+func (b *FileBackend[K, V]) read(path string) (record[K, V], error) {
+	var rec record[K, V]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, fmt.Errorf("cache: failed to read entry: %w", err)
+	}
+
+	if err := b.codec.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return rec, fmt.Errorf("cache: failed to decode entry: %w", err)
+	}
+	return rec, nil
+}
+
+// now returns the time of the clock of the backend, in nanoseconds.
+func (b *FileBackend[K, V]) now() int64 {
+	return b.clock.Now().UnixNano()
+}
@@ -0,0 +1,214 @@
+package cache
+
+import "errors"
+
+// ErrNotLoaded is returned by GetOrLoad to the callers that were waiting on a
+// load of GetOrLoadMany that did not return their key.
+var ErrNotLoaded = errors.New("cache: key not returned by load function")
+
+// GetMany returns the values of keys in the cache, and whether each of them
+// was found, looking all of them up under a single lock.
+//
+// Like Get, GetMany only locks the cache for reading, unless it has a Policy
+// or uses sliding expiration.
+func (c *Cache[K, V]) GetMany(keys []K) ([]V, []bool) {
+	vs := make([]V, len(keys))
+	found := make([]bool, len(keys))
+
+	if c.policy != nil || c.sliding {
+		c.locker.Lock()
+		defer c.unlockAndNotify()
+		for i, k := range keys {
+			vs[i], found[i] = c.get(k)
+			c.stats.hit(found[i])
+		}
+		return vs, found
+	}
+
+	var expired []K
+	c.locker.RLock()
+	now := c.now()
+	for i, k := range keys {
+		it, ok := c.cache[k]
+		switch {
+		case !ok:
+		case it.expired(now):
+			expired = append(expired, k)
+		default:
+			vs[i], found[i] = it.v, true
+			c.refreshIfStale(it, now)
+		}
+		c.stats.hit(found[i])
+	}
+	c.locker.RUnlock()
+
+	if len(expired) > 0 {
+		c.locker.Lock()
+		now := c.now()
+		for _, k := range expired {
+			if it, ok := c.cache[k]; ok && it.expired(now) {
+				c.delete(k, ReasonExpired)
+			}
+		}
+		c.unlockAndNotify()
+	}
+	return vs, found
+}
+
+// SetMany sets all entries of m to the cache under a single lock, each of them
+// cached for the cacheFor passed to New.
+func (c *Cache[K, V]) SetMany(m map[K]V) {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	for k, v := range m {
+		c.set(k, v, c.cacheFor)
+	}
+}
+
+// DeleteMany deletes keys from the cache under a single lock, reporting whether
+// each of them was found. The errors cached for them by GetOrLoad, if any, are
+// also deleted.
+//
+// If the cache uses an invalidation Bus, keys are also deleted from the other
+// caches on the bus.
+func (c *Cache[K, V]) DeleteMany(keys []K) []bool {
+	c.loadMu.Lock()
+	for _, k := range keys {
+		delete(c.loadErrs, k)
+	}
+	c.loadMu.Unlock()
+
+	found := make([]bool, len(keys))
+	c.locker.Lock()
+	now := c.now()
+	for i, k := range keys {
+		if it, ok := c.cache[k]; ok {
+			found[i] = !it.expired(now)
+			c.delete(k, ReasonDeleted)
+		}
+	}
+	c.unlockAndNotify()
+
+	for _, k := range keys {
+		c.publishDelete(k)
+	}
+	return found
+}
+
+// GetOrLoadMany returns the values of keys in the cache. The keys that are not
+// found are loaded with a single call of load, and the values it returns are
+// set to the cache.
+//
+// Keys that load does not return are left out of the returned map, and so are
+// the keys whose load failed, in which case the first error is returned along
+// with the values that were found.
+//
+// Keys already being loaded by GetOrLoad or GetOrLoadMany are not passed to
+// load, their result is waited for instead. Like GetOrLoad, GetOrLoadMany
+// caches the errors of load if WithErrorTTL is used, and so the keys that load
+// did not return are not loaded again until the error TTL passes.
+func (c *Cache[K, V]) GetOrLoadMany(keys []K, load func([]K) (map[K]V, error)) (map[K]V, error) {
+	vs, found := c.GetMany(keys)
+	m := make(map[K]V, len(keys))
+	for i, k := range keys {
+		if found[i] {
+			m[k] = vs[i]
+		}
+	}
+
+	var (
+		firstErr error
+		missing  []K
+		calls    []*call[V]
+		waiting  = make(map[K]*call[V])
+	)
+	c.loadMu.Lock()
+	now := c.now()
+	for i, k := range keys {
+		if found[i] {
+			continue
+		}
+		if _, ok := waiting[k]; ok {
+			continue
+		}
+
+		if e, ok := c.loadErrs[k]; ok {
+			if now < e.exp {
+				if firstErr == nil && !errors.Is(e.err, ErrNotLoaded) {
+					firstErr = e.err
+				}
+				continue
+			}
+			delete(c.loadErrs, k)
+		}
+
+		cl, ok := c.calls[k]
+		if !ok {
+			cl = c.newCall(k)
+			missing = append(missing, k)
+			calls = append(calls, cl)
+		}
+		waiting[k] = cl
+	}
+	c.loadMu.Unlock()
+
+	if len(missing) > 0 {
+		c.loadMany(missing, calls, load)
+	}
+
+	for k, cl := range waiting {
+		cl.wg.Wait()
+		switch {
+		case cl.err == nil:
+			m[k] = cl.v
+		case firstErr == nil && !errors.Is(cl.err, ErrNotLoaded):
+			firstErr = cl.err
+		}
+	}
+	return m, firstErr
+}
+
+// loadMany calls f with keys, sets the values it returns to the cache, stores
+// them in calls and wakes up the callers waiting on them.
+func (c *Cache[K, V]) loadMany(keys []K, calls []*call[V], f func([]K) (map[K]V, error)) {
+	var (
+		m            map[K]V
+		err          error
+		normalReturn bool
+	)
+	defer func() {
+		if !normalReturn {
+			err = ErrLoaderPanicked
+		}
+
+		c.loadMu.Lock()
+		for i, k := range keys {
+			cl := calls[i]
+			if err != nil {
+				cl.err = err
+			} else if v, ok := m[k]; ok {
+				cl.v = v
+			} else {
+				cl.err = ErrNotLoaded
+			}
+			c.finishCall(k, cl)
+		}
+		c.loadMu.Unlock()
+
+		for _, cl := range calls {
+			cl.wg.Done()
+		}
+	}()
+
+	m, err = f(keys)
+	if err == nil {
+		c.locker.Lock()
+		for _, k := range keys {
+			if v, ok := m[k]; ok {
+				c.set(k, v, c.cacheFor)
+			}
+		}
+		c.unlockAndNotify()
+	}
+	normalReturn = true
+}
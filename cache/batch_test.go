@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheBatch(t *testing.T) {
+	errLoad := errors.New("load failed")
+
+	for _, tc := range []struct {
+		name string
+		opts []Option[string, int]
+		f    func(*Cache[string, int]) error
+	}{
+		{
+			"get many",
+			nil,
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				setExpired(c, "baz", 3)
+
+				vs, found := c.GetMany([]string{"foo", "baz", "qux", "bar"})
+				if want := []int{1, 0, 0, 2}; !slices.Equal(vs, want) {
+					return fmt.Errorf("expected values %v but got %v instead", want, vs)
+				}
+				if want := []bool{true, false, false, true}; !slices.Equal(found, want) {
+					return fmt.Errorf("expected found %v but got %v instead", want, found)
+				}
+
+				if s := c.Stats(); s.Hits != 2 || s.Misses != 2 {
+					return fmt.Errorf("expected 2 hits and 2 misses but got %d and %d instead", s.Hits, s.Misses)
+				}
+
+				if c.Len() != 2 {
+					return fmt.Errorf("expired key baz was not deleted")
+				}
+
+				return nil
+			},
+		},
+		{
+			"get many with policy",
+			[]Option[string, int]{WithCapacity[string, int](10)},
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				setExpired(c, "baz", 3)
+
+				vs, found := c.GetMany([]string{"foo", "baz", "qux", "bar"})
+				if want := []int{1, 0, 0, 2}; !slices.Equal(vs, want) {
+					return fmt.Errorf("expected values %v but got %v instead", want, vs)
+				}
+				if want := []bool{true, false, false, true}; !slices.Equal(found, want) {
+					return fmt.Errorf("expected found %v but got %v instead", want, found)
+				}
+
+				if s := c.Stats(); s.Hits != 2 || s.Misses != 2 {
+					return fmt.Errorf("expected 2 hits and 2 misses but got %d and %d instead", s.Hits, s.Misses)
+				}
+
+				if c.Len() != 2 {
+					return fmt.Errorf("expired key baz was not deleted")
+				}
+
+				return nil
+			},
+		},
+		{
+			"set many",
+			nil,
+			func(c *Cache[string, int]) error {
+				c.SetMany(map[string]int{"foo": 1, "bar": 2})
+
+				if v, ok := c.Get("foo"); !ok || v != 1 {
+					return fmt.Errorf("expected 1 but got %d, %t instead", v, ok)
+				}
+
+				if v, ok := c.Get("bar"); !ok || v != 2 {
+					return fmt.Errorf("expected 2 but got %d, %t instead", v, ok)
+				}
+
+				return nil
+			},
+		},
+		{
+			"delete many",
+			nil,
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				c.Set("baz", 3)
+				setExpired(c, "qux", 4)
+
+				found := c.DeleteMany([]string{"foo", "qux", "quux", "baz"})
+				if want := []bool{true, false, false, true}; !slices.Equal(found, want) {
+					return fmt.Errorf("expected found %v but got %v instead", want, found)
+				}
+
+				if c.Len() != 1 || !c.Contains("bar") {
+					return fmt.Errorf("expected only bar in the cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"get or load many batches misses",
+			nil,
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+
+				var batches [][]string
+				load := func(keys []string) (map[string]int, error) {
+					batches = append(batches, slices.Clone(keys))
+					m := make(map[string]int)
+					for _, k := range keys {
+						if k != "missing" {
+							m[k] = len(k)
+						}
+					}
+					return m, nil
+				}
+
+				m, err := c.GetOrLoadMany([]string{"foo", "bar", "quux", "bar", "missing"}, load)
+				if err != nil {
+					return err
+				}
+
+				if want := map[string]int{"foo": 1, "bar": 3, "quux": 4}; fmt.Sprint(m) != fmt.Sprint(want) {
+					return fmt.Errorf("expected %v but got %v instead", want, m)
+				}
+
+				if len(batches) != 1 {
+					return fmt.Errorf("expected load to be called once but it was called %d times", len(batches))
+				}
+
+				if want := []string{"bar", "quux", "missing"}; !slices.Equal(batches[0], want) {
+					return fmt.Errorf("expected load of %v but got %v instead", want, batches[0])
+				}
+
+				if v, ok := c.Get("quux"); !ok || v != 4 {
+					return fmt.Errorf("loaded value was not set to the cache")
+				}
+
+				if c.Contains("missing") {
+					return fmt.Errorf("key missing found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"get or load many errors",
+			[]Option[string, int]{WithErrorTTL[string, int](time.Hour)},
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+
+				var calls int
+				load := func(keys []string) (map[string]int, error) {
+					calls++
+					return nil, errLoad
+				}
+
+				for i := 0; i < 2; i++ {
+					m, err := c.GetOrLoadMany([]string{"foo", "bar"}, load)
+					if !errors.Is(err, errLoad) {
+						return fmt.Errorf("expected errLoad but got %v instead", err)
+					}
+					if len(m) != 1 || m["foo"] != 1 {
+						return fmt.Errorf("expected only foo to be returned but got %v instead", m)
+					}
+				}
+
+				if calls != 1 {
+					return fmt.Errorf("expected load to be called once but it was called %d times", calls)
+				}
+
+				if _, err := c.GetOrLoad("bar", func(string) (int, error) { return 2, nil }); !errors.Is(err, errLoad) {
+					return fmt.Errorf("expected cached errLoad but got %v instead", err)
+				}
+
+				return nil
+			},
+		},
+		{
+			"get or load many joins loads",
+			nil,
+			func(c *Cache[string, int]) error {
+				started := make(chan struct{})
+				release := make(chan struct{})
+				wg := new(sync.WaitGroup)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.GetOrLoad("foo", func(string) (int, error) {
+						close(started)
+						<-release
+						return 1, nil
+					})
+				}()
+				<-started
+
+				var loaded []string
+				done := make(chan error, 1)
+				go func() {
+					m, err := c.GetOrLoadMany([]string{"foo", "bar"}, func(keys []string) (map[string]int, error) {
+						loaded = append(loaded, keys...)
+						return map[string]int{"bar": 2}, nil
+					})
+					if err == nil && (m["foo"] != 1 || m["bar"] != 2) {
+						err = fmt.Errorf("expected foo 1 and bar 2 but got %v instead", m)
+					}
+					done <- err
+				}()
+
+				close(release)
+				wg.Wait()
+				if err := <-done; err != nil {
+					return err
+				}
+
+				sort.Strings(loaded)
+				if !slices.Equal(loaded, []string{"bar"}) {
+					return fmt.Errorf("expected only bar to be loaded but got %v instead", loaded)
+				}
+
+				return nil
+			},
+		},
+	} {
+		c := New(0, tc.opts...)
+		if err := tc.f(c); err != nil {
+			t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+		}
+		c.Close()
+	}
+}
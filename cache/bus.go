@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// Invalidation tells the caches subscribed to a Bus to delete a key, or all of
+// their keys.
+type Invalidation struct {
+	// Cache is the name of the caches that must handle the invalidation.
+	Cache string
+	// Origin identifies the cache that published the invalidation, so that it
+	// can ignore it.
+	Origin string
+	// Key is the key to delete, encoded with the Codec of the caches.
+	Key []byte
+	// All means all keys must be deleted, in which case Key is ignored.
+	All bool
+	// Tag, if not empty, means the entries tagged with it must be deleted, in
+	// which case Key is ignored. See Cache.InvalidateTag.
+	Tag string
+	// Prefix means Key is the prefix of the keys to delete. It is only
+	// handled by caches with string keys. See DeletePrefix.
+	Prefix bool
+}
+
+// Bus delivers invalidations between caches, usually running in different
+// processes, so that deleting a key from one of them deletes it from all.
+//
+// A Bus must be safe for concurrent use.
+type Bus interface {
+	// Publish sends inv to the subscribers of the bus. It may or may not be
+	// delivered to the subscribers of the publisher process.
+	Publish(inv Invalidation) error
+
+	// Subscribe makes f be called for every invalidation received by the bus,
+	// until the returned func is called.
+	Subscribe(f func(Invalidation)) (unsubscribe func())
+}
+
+// subscribers are the subscribers of a Bus.
+type subscribers struct {
+	mu   sync.RWMutex
+	subs map[int]func(Invalidation)
+	next int
+}
+
+func (s *subscribers) subscribe(f func(Invalidation)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[int]func(Invalidation))
+	}
+	id := s.next
+	s.next++
+	s.subs[id] = f
+
+	return func() {
+		s.mu.Lock()
+		delete(s.subs, id)
+		s.mu.Unlock()
+	}
+}
+
+func (s *subscribers) deliver(inv Invalidation) {
+	s.mu.RLock()
+	subs := make([]func(Invalidation), 0, len(s.subs))
+	for _, f := range s.subs {
+		subs = append(subs, f)
+	}
+	s.mu.RUnlock()
+
+	for _, f := range subs {
+		f(inv)
+	}
+}
+
+// NewLocalBus returns a Bus that delivers invalidations to the caches of the
+// current process.
+func NewLocalBus() *LocalBus {
+	return new(LocalBus)
+}
+
+// LocalBus is a Bus for caches running in the same process. Invalidations are
+// delivered synchronously by Publish.
+type LocalBus struct {
+	subs subscribers
+}
+
+// Publish implements Bus.
+func (b *LocalBus) Publish(inv Invalidation) error {
+	b.subs.deliver(inv)
+	return nil
+}
+
+// Subscribe implements Bus.
+func (b *LocalBus) Subscribe(f func(Invalidation)) func() {
+	return b.subs.subscribe(f)
+}
+
+// busID returns a random identifier for a cache publishing to a Bus.
+func busID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// publish publishes inv to the bus of the cache, if any.
+//
+// Delete and Wipe can not report errors, so errors from the bus are dropped.
+// Buses should retry failed deliveries themselves when it makes sense.
+func (c *Cache[K, V]) publish(inv Invalidation) {
+	if c.bus == nil {
+		return
+	}
+	inv.Cache, inv.Origin = c.busName, c.busID
+	_ = c.bus.Publish(inv)
+}
+
+// publishDelete publishes the invalidation of k to the bus of the cache, if
+// any.
+func (c *Cache[K, V]) publishDelete(k K) {
+	c.publishKey(k, false)
+}
+
+// publishPrefix publishes the invalidation of the keys starting with prefix to
+// the bus of the cache, if any.
+func (c *Cache[K, V]) publishPrefix(prefix string) {
+	c.publishKey(prefix, true)
+}
+
+func (c *Cache[K, V]) publishKey(k any, prefix bool) {
+	if c.bus == nil {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if err := c.busCodec.NewEncoder(buf).Encode(k); err != nil {
+		return
+	}
+	c.publish(Invalidation{Key: buf.Bytes(), Prefix: prefix})
+}
+
+// invalidate handles an invalidation received from the bus of the cache.
+func (c *Cache[K, V]) invalidate(inv Invalidation) {
+	if inv.Cache != c.busName || inv.Origin == c.busID {
+		return
+	}
+
+	switch {
+	case inv.All:
+		c.wipeLocal()
+		return
+	case inv.Tag != "":
+		c.invalidateTagLocal(inv.Tag)
+		return
+	}
+
+	var k K
+	if err := c.busCodec.NewDecoder(bytes.NewReader(inv.Key)).Decode(&k); err != nil {
+		return
+	}
+
+	if inv.Prefix {
+		if sc, ok := any(c).(*Cache[string, V]); ok {
+			deletePrefix(sc, any(k).(string))
+		}
+		return
+	}
+	c.deleteLocal(k)
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// tcpDialTimeout is how long TCPBus waits to connect to a peer.
+	tcpDialTimeout = 5 * time.Second
+	// tcpWriteTimeout is how long TCPBus waits to send an invalidation to a
+	// peer.
+	tcpWriteTimeout = 5 * time.Second
+	// tcpQueueSize is how many invalidations TCPBus queues for each peer
+	// before dropping them.
+	tcpQueueSize = 1024
+)
+
+// NewTCPBus returns a TCPBus listening for invalidations on addr and
+// publishing them to peers.
+//
+// Every replica must list the others as its peers, as a TCPBus does not
+// forward the invalidations it receives.
+//
+// The bus does not authenticate nor encrypt its connections: anyone who can
+// connect to addr can delete the entries of the caches on the bus, or wipe
+// them. addr must only be reachable by trusted peers, such as from a private
+// network.
+func NewTCPBus(addr string, peers ...string) (*TCPBus, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cache: failed to listen for invalidations: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &TCPBus{
+		ln:     ln,
+		ctx:    ctx,
+		cancel: cancel,
+		peers:  make(map[string]chan []byte, len(peers)),
+		conns:  make(map[net.Conn]struct{}),
+	}
+	for _, p := range peers {
+		b.addPeer(p)
+	}
+
+	b.wg.Add(1)
+	go b.accept()
+	return b, nil
+}
+
+// TCPBus is a Bus that sends invalidations to a fixed set of peers over TCP,
+// one JSON object per line.
+//
+// Publish does not wait for the invalidations to be sent: they are queued for
+// each peer and sent in the background, so a slow or unreachable peer does not
+// slow down the caches. Connections to the peers are made lazily and kept
+// open, and a peer is redialed once if its connection fails. Invalidations that
+// can not be sent, even then, are dropped.
+type TCPBus struct {
+	ln     net.Listener
+	subs   subscribers
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// peers maps the address of each peer to the queue of the invalidations
+	// to send to it.
+	peers  map[string]chan []byte
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// Addr returns the address the bus listens on.
+func (b *TCPBus) Addr() net.Addr {
+	return b.ln.Addr()
+}
+
+// AddPeer makes the bus publish invalidations to addr too.
+func (b *TCPBus) AddPeer(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.addPeer(addr)
+	}
+}
+
+// addPeer must be called with b.mu held, or before the bus is used.
+func (b *TCPBus) addPeer(addr string) {
+	if _, ok := b.peers[addr]; ok {
+		return
+	}
+	queue := make(chan []byte, tcpQueueSize)
+	b.peers[addr] = queue
+	b.wg.Add(1)
+	go b.send(addr, queue)
+}
+
+// Publish implements Bus. It queues inv to be sent to all the peers, returning
+// an error for the ones whose queue is full, which do not get inv.
+func (b *TCPBus) Publish(inv Invalidation) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("cache: failed to encode invalidation: %w", err)
+	}
+	data = append(data, '\n')
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return net.ErrClosed
+	}
+
+	var errs []error
+	for addr, queue := range b.peers {
+		select {
+		case queue <- data:
+		default:
+			errs = append(errs, fmt.Errorf("cache: invalidation queue of peer %s is full", addr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send sends the invalidations of queue to addr until the bus is closed.
+func (b *TCPBus) send(addr string, queue <-chan []byte) {
+	defer b.wg.Done()
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case data := <-queue:
+			conn = b.write(conn, addr, data)
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// write writes data to conn, dialing addr if conn is nil or fails, and returns
+// the connection to write to next, which is nil if data could not be sent.
+func (b *TCPBus) write(conn net.Conn, addr string, data []byte) net.Conn {
+	dialer := net.Dialer{Timeout: tcpDialTimeout}
+	for attempt := 0; attempt < 2; attempt++ {
+		if conn == nil {
+			var err error
+			if conn, err = dialer.DialContext(b.ctx, "tcp", addr); err != nil {
+				return nil
+			}
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+		if _, err := conn.Write(data); err == nil {
+			return conn
+		}
+		conn.Close()
+		conn = nil
+	}
+	return nil
+}
+
+// Subscribe implements Bus.
+func (b *TCPBus) Subscribe(f func(Invalidation)) func() {
+	return b.subs.subscribe(f)
+}
+
+// Close stops listening and closes all connections of the bus. The
+// invalidations that were not sent yet are dropped.
+func (b *TCPBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.cancel()
+	err := b.ln.Close()
+	for conn := range b.conns {
+		conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return err
+}
+
+func (b *TCPBus) accept() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			conn.Close()
+			return
+		}
+		b.conns[conn] = struct{}{}
+		b.wg.Add(1)
+		b.mu.Unlock()
+
+		go b.serve(conn)
+	}
+}
+
+func (b *TCPBus) serve(conn net.Conn) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.conns, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var inv Invalidation
+		if err := dec.Decode(&inv); err != nil {
+			return
+		}
+		b.subs.deliver(inv)
+	}
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBus(t *testing.T) {
+	for _, bus := range []struct {
+		name string
+		new  func(t *testing.T) (Bus, Bus)
+	}{
+		{
+			"local",
+			func(t *testing.T) (Bus, Bus) {
+				b := NewLocalBus()
+				return b, b
+			},
+		},
+		{
+			"tcp",
+			func(t *testing.T) (Bus, Bus) {
+				b1, err := NewTCPBus("127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { b1.Close() })
+
+				b2, err := NewTCPBus("127.0.0.1:0", b1.Addr().String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { b2.Close() })
+
+				b1.AddPeer(b2.Addr().String())
+				return b1, b2
+			},
+		},
+	} {
+		for _, tc := range []struct {
+			name string
+			f    func(c1, c2, other *Cache[string, int]) error
+		}{
+			{
+				"delete propagates",
+				func(c1, c2, _ *Cache[string, int]) error {
+					c1.Set("foo", 1)
+					c2.Set("foo", 1)
+					c2.Set("bar", 2)
+
+					c1.Delete("foo")
+					if !waitFor(func() bool { return !c2.Contains("foo") }) {
+						return fmt.Errorf("foo was not deleted from the other cache")
+					}
+
+					if !c2.Contains("bar") {
+						return fmt.Errorf("bar was deleted from the other cache")
+					}
+
+					return nil
+				},
+			},
+			{
+				"wipe propagates",
+				func(c1, c2, _ *Cache[string, int]) error {
+					c2.Set("foo", 1)
+					c2.Set("bar", 2)
+
+					c1.Wipe()
+					if !waitFor(func() bool { return c2.Len() == 0 }) {
+						return fmt.Errorf("the other cache was not wiped")
+					}
+
+					return nil
+				},
+			},
+			{
+				"tag invalidation propagates",
+				func(c1, c2, _ *Cache[string, int]) error {
+					c2.SetWithTags("foo", 1, "a")
+					c2.SetWithTags("bar", 2, "b")
+
+					c1.InvalidateTag("a")
+					if !waitFor(func() bool { return !c2.Contains("foo") }) {
+						return fmt.Errorf("foo was not deleted from the other cache")
+					}
+
+					if !c2.Contains("bar") {
+						return fmt.Errorf("bar was deleted from the other cache")
+					}
+
+					return nil
+				},
+			},
+			{
+				"prefix deletion propagates",
+				func(c1, c2, _ *Cache[string, int]) error {
+					c2.Set("user:1", 1)
+					c2.Set("order:1", 2)
+
+					DeletePrefix(c1, "user:")
+					if !waitFor(func() bool { return !c2.Contains("user:1") }) {
+						return fmt.Errorf("user:1 was not deleted from the other cache")
+					}
+
+					if !c2.Contains("order:1") {
+						return fmt.Errorf("order:1 was deleted from the other cache")
+					}
+
+					return nil
+				},
+			},
+			{
+				"ignores other caches",
+				func(c1, c2, other *Cache[string, int]) error {
+					other.Set("foo", 1)
+					c2.Set("foo", 1)
+
+					c1.Delete("foo")
+					if !waitFor(func() bool { return !c2.Contains("foo") }) {
+						return fmt.Errorf("foo was not deleted from the other cache")
+					}
+
+					if !other.Contains("foo") {
+						return fmt.Errorf("foo was deleted from a cache with another name")
+					}
+
+					return nil
+				},
+			},
+			{
+				"does not delete twice",
+				func(c1, c2, _ *Cache[string, int]) error {
+					c1.Set("foo", 1)
+					c1.Delete("foo")
+					c1.Set("foo", 2)
+
+					if !waitFor(func() bool { return !c2.Contains("foo") }) {
+						return fmt.Errorf("foo was not deleted from the other cache")
+					}
+
+					if v, ok := c1.Get("foo"); !ok || v != 2 {
+						return fmt.Errorf("expected 2 but got %d, %t instead", v, ok)
+					}
+
+					return nil
+				},
+			},
+		} {
+			b1, b2 := bus.new(t)
+			c1 := New(0, WithInvalidationBus[string, int](b1, "test", JSONCodec))
+			c2 := New(0, WithInvalidationBus[string, int](b2, "test", JSONCodec))
+			other := New(0, WithInvalidationBus[string, int](b2, "other", JSONCodec))
+
+			if err := tc.f(c1, c2, other); err != nil {
+				t.Errorf("\ntest '%s/%s' failed\nerr: %v", bus.name, tc.name, err)
+			}
+
+			c1.Close()
+			c2.Close()
+			other.Close()
+		}
+	}
+}
+
+func TestTCPBusUnreachablePeer(t *testing.T) {
+	// Connections to this address hang or fail, depending on the network.
+	b, err := NewTCPBus("127.0.0.1:0", "10.255.255.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	c := New(0, WithInvalidationBus[string, int](b, "test", JSONCodec))
+	defer c.Close()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		c.Set("foo", i)
+		c.Delete("foo")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("\ndeletes were blocked by the unreachable peer for %v", d)
+	}
+}
+
+func TestUDPBus(t *testing.T) {
+	b, err := NewUDPBus("239.0.0.1:18018")
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	defer b.Close()
+
+	got := make(chan Invalidation, 1)
+	defer b.Subscribe(func(inv Invalidation) {
+		select {
+		case got <- inv:
+		default:
+		}
+	})()
+
+	want := Invalidation{Cache: "test", Origin: "me", Key: []byte("foo")}
+	if err := b.Publish(want); err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+
+	select {
+	case inv := <-got:
+		if inv.Cache != want.Cache || inv.Origin != want.Origin || string(inv.Key) != string(want.Key) {
+			t.Errorf("expected %+v but got %+v instead", want, inv)
+		}
+	case <-time.After(time.Second):
+		t.Skip("multicast loopback is not available")
+	}
+}
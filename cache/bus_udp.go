@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// udpMaxDatagram is the size of the largest invalidation a UDPBus can receive.
+const udpMaxDatagram = 64 << 10
+
+// NewUDPBus returns a UDPBus publishing to and receiving from the multicast
+// group at addr, e.g. "239.0.0.1:9999".
+func NewUDPBus(addr string) (*UDPBus, error) {
+	gaddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cache: failed to resolve multicast group: %w", err)
+	}
+
+	rconn, err := net.ListenMulticastUDP("udp", nil, gaddr)
+	if err != nil {
+		return nil, fmt.Errorf("cache: failed to join multicast group: %w", err)
+	}
+	rconn.SetReadBuffer(udpMaxDatagram)
+
+	wconn, err := net.DialUDP("udp", nil, gaddr)
+	if err != nil {
+		rconn.Close()
+		return nil, fmt.Errorf("cache: failed to dial multicast group: %w", err)
+	}
+
+	b := &UDPBus{rconn: rconn, wconn: wconn}
+	b.wg.Add(1)
+	go b.read()
+	return b, nil
+}
+
+// UDPBus is a Bus that sends invalidations to a UDP multicast group, one JSON
+// object per datagram.
+//
+// Delivery is best effort: datagrams may be lost, and invalidations with keys
+// too large to fit in a datagram can not be published.
+type UDPBus struct {
+	rconn *net.UDPConn
+	wconn *net.UDPConn
+	subs  subscribers
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+// Publish implements Bus.
+func (b *UDPBus) Publish(inv Invalidation) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("cache: failed to encode invalidation: %w", err)
+	}
+	if len(data) > udpMaxDatagram {
+		return fmt.Errorf("cache: invalidation of %d bytes does not fit in a datagram", len(data))
+	}
+
+	if _, err := b.wconn.Write(data); err != nil {
+		return fmt.Errorf("cache: failed to publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Subscribe implements Bus.
+func (b *UDPBus) Subscribe(f func(Invalidation)) func() {
+	return b.subs.subscribe(f)
+}
+
+// Close leaves the multicast group and closes the connections of the bus.
+func (b *UDPBus) Close() error {
+	var err error
+	b.once.Do(func() {
+		err = b.rconn.Close()
+		b.wconn.Close()
+		b.wg.Wait()
+	})
+	return err
+}
+
+func (b *UDPBus) read() {
+	defer b.wg.Done()
+	buf := make([]byte, udpMaxDatagram)
+	for {
+		n, _, err := b.rconn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		var inv Invalidation
+		if err := json.Unmarshal(buf[:n], &inv); err != nil {
+			continue
+		}
+		b.subs.deliver(inv)
+	}
+}
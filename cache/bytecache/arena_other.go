@@ -0,0 +1,12 @@
+//go:build !unix
+
+package bytecache
+
+// allocArena allocates an arena of n bytes. The arena holds no pointers, so
+// the GC does not scan it.
+func allocArena(n int) []byte {
+	return make([]byte, n)
+}
+
+// freeArena releases an arena allocated by allocArena.
+func freeArena([]byte) {}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package bytecache
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// allocArena allocates an arena of n bytes outside of the Go heap.
+func allocArena(n int) []byte {
+	b, err := syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		panic(fmt.Sprintf("bytecache: failed to allocate %d bytes: %v", n, err))
+	}
+	return b
+}
+
+// freeArena releases an arena allocated by allocArena.
+func freeArena(b []byte) {
+	if err := syscall.Munmap(b); err != nil {
+		panic(fmt.Sprintf("bytecache: failed to free arena: %v", err))
+	}
+}
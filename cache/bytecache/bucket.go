@@ -0,0 +1,195 @@
+package bytecache
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+const (
+	// headerSize is the size of the header of an entry: the length of its
+	// key and value, as uint32s, and when it expires, as an int64.
+	headerSize = 16
+
+	// offsetBits is how many bits of a position hold the offset of the entry
+	// in the arena. The others hold the generation of the arena.
+	offsetBits = 40
+	offsetMask = 1<<offsetBits - 1
+	genMask    = 1<<(64-offsetBits) - 1
+)
+
+// result is the result of a lookup.
+type result uint8
+
+const (
+	missing result = iota
+	found
+	expired
+)
+
+// bucket is a shard of a Cache.
+type bucket struct {
+	mu    sync.RWMutex
+	arena []byte
+	// m maps the hashes of the keys to the position of their entries, which
+	// is gen<<offsetBits | offset.
+	m map[uint64]uint64
+	// idx is the offset where the next entry will be written.
+	idx uint64
+	// gen is the generation of the arena, incremented every time idx wraps
+	// around. Only the entries of the current generation written before idx
+	// and those of the previous one written after it were not overwritten.
+	gen uint64
+}
+
+func (b *bucket) reset() {
+	b.m = make(map[uint64]uint64)
+	b.idx = 0
+	b.gen = 1
+}
+
+// set writes the entry to the arena and returns how many entries were evicted
+// to make room for it.
+//
+// If the entry does not fit in the arena, the old value of k is evicted
+// instead.
+func (b *bucket) set(h uint64, k string, v []byte, exp int64) int {
+	size := uint64(headerSize + len(k) + len(v))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if size > uint64(len(b.arena)) || uint64(len(k)) > math.MaxUint32 || uint64(len(v)) > math.MaxUint32 {
+		if _, _, ok := b.lookup(h, k); ok {
+			delete(b.m, h)
+			return 1
+		}
+		return 0
+	}
+
+	var evicted int
+	if b.idx+size > uint64(len(b.arena)) {
+		b.idx = 0
+		b.gen = (b.gen + 1) & genMask
+		evicted = b.clean()
+	}
+
+	e := b.arena[b.idx : b.idx+size]
+	binary.LittleEndian.PutUint32(e[0:], uint32(len(k)))
+	binary.LittleEndian.PutUint32(e[4:], uint32(len(v)))
+	binary.LittleEndian.PutUint64(e[8:], uint64(exp))
+	copy(e[headerSize:], k)
+	copy(e[headerSize+len(k):], v)
+
+	b.m[h] = b.gen<<offsetBits | b.idx
+	b.idx += size
+	return evicted
+}
+
+// get appends the value of k to dst.
+func (b *bucket) get(dst []byte, h uint64, k string, now int64) ([]byte, result) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	v, exp, ok := b.lookup(h, k)
+	switch {
+	case !ok:
+		return dst, missing
+	case exp != 0 && now > exp:
+		return dst, expired
+	default:
+		return append(dst, v...), found
+	}
+}
+
+// deleteExpired deletes k if it is still expired, reporting if it did.
+func (b *bucket) deleteExpired(h uint64, k string, now int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exp, ok := b.lookup(h, k); ok && exp != 0 && now > exp {
+		delete(b.m, h)
+		return true
+	}
+	return false
+}
+
+// delete deletes k, reporting if it was found.
+func (b *bucket) delete(h uint64, k string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, _, ok := b.lookup(h, k); ok {
+		delete(b.m, h)
+		return true
+	}
+	return false
+}
+
+// wipe deletes all entries and returns how many there were.
+func (b *bucket) wipe() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.m)
+	if b.arena != nil {
+		b.reset()
+	}
+	return n
+}
+
+func (b *bucket) len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.m)
+}
+
+func (b *bucket) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.arena = nil
+	b.m = nil
+}
+
+// lookup returns the value of k and when it expires. The value points into
+// the arena, so it must be copied before b.mu is unlocked.
+//
+// lookup must be called with b.mu held.
+func (b *bucket) lookup(h uint64, k string) ([]byte, int64, bool) {
+	pos, ok := b.m[h]
+	if !ok || !b.valid(pos) {
+		return nil, 0, false
+	}
+
+	e := b.arena[pos&offsetMask:]
+	kl := uint64(binary.LittleEndian.Uint32(e[0:]))
+	vl := uint64(binary.LittleEndian.Uint32(e[4:]))
+	exp := int64(binary.LittleEndian.Uint64(e[8:]))
+	if string(e[headerSize:headerSize+kl]) != k {
+		// Another key with the same hash.
+		return nil, 0, false
+	}
+	return e[headerSize+kl : headerSize+kl+vl], exp, true
+}
+
+// valid reports whether the entry at pos was not overwritten yet.
+//
+// valid must be called with b.mu held.
+func (b *bucket) valid(pos uint64) bool {
+	gen, off := pos>>offsetBits, pos&offsetMask
+	if gen == b.gen {
+		return off < b.idx
+	}
+	return gen == (b.gen-1)&genMask && off >= b.idx
+}
+
+// clean deletes the positions of the overwritten entries from b.m and returns
+// how many were deleted.
+//
+// clean must be called with b.mu held for writing.
+func (b *bucket) clean() int {
+	var n int
+	for h, pos := range b.m {
+		if !b.valid(pos) {
+			delete(b.m, h)
+			n++
+		}
+	}
+	return n
+}
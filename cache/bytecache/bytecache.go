@@ -0,0 +1,246 @@
+// Package bytecache provides a cache of byte slices that does not burden the Go
+// GC, no matter how many entries it holds.
+//
+// Entries are appended to large byte arenas, allocated outside of the Go heap
+// where possible, and indexed by maps of key hashes to arena offsets. Neither
+// the arenas nor the maps hold pointers, so the GC never scans them.
+//
+// The arenas are ring buffers: when one is full, the oldest entries are
+// overwritten by new ones, so the cache never uses more than the memory it was
+// created with.
+package bytecache
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache"
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+const (
+	// maxBuckets is the maximum number of buckets of a Cache. Each bucket
+	// has its own lock, arena and index.
+	maxBuckets = 512
+	// minBucketSize is the minimum size of the arena of a bucket.
+	minBucketSize = 64 << 10
+)
+
+// Option configures a Cache created by New.
+type Option func(*Cache)
+
+// WithClock makes the cache tell the time with clk instead of the system
+// clock, which is mostly useful to test expiry with a fake clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *Cache) {
+		c.clock = clk
+	}
+}
+
+// New creates a new Cache storing up to maxBytes of keys and values, each
+// entry cached for cacheFor. Set cacheFor to 0 to cache entries until they are
+// overwritten by newer ones.
+//
+// maxBytes is rounded up to at least 64KiB. The memory of the cache is
+// reserved by New, but the operating system only commits it as it is written.
+//
+// The cache can be further configured with opts.
+func New(maxBytes int, cacheFor time.Duration, opts ...Option) *Cache {
+	n := maxBytes / minBucketSize
+	if n < 1 {
+		n = 1
+	} else if n > maxBuckets {
+		n = maxBuckets
+	}
+	// Round n down to a power of two, so that a bucket is picked by masking
+	// the hash of its keys.
+	n = 1 << (bits.Len(uint(n)) - 1)
+
+	bucketSize := (maxBytes + n - 1) / n
+	if bucketSize < minBucketSize {
+		bucketSize = minBucketSize
+	}
+
+	c := &Cache{
+		arena:      allocArena(n * bucketSize),
+		bucketSize: bucketSize,
+		buckets:    make([]bucket, n),
+		mask:       uint64(n - 1),
+		seed:       maphash.MakeSeed(),
+		cacheFor:   cacheFor,
+		clock:      clock.Real{},
+	}
+	for i := range c.buckets {
+		b := &c.buckets[i]
+		b.arena = c.arena[i*bucketSize : (i+1)*bucketSize : (i+1)*bucketSize]
+		b.reset()
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Cache is a thread safe cache of byte slices with string keys.
+//
+// Unlike cache.Cache, a Cache holds copies of the values it is given and
+// returns copies of the values it holds.
+type Cache struct {
+	arena      []byte
+	bucketSize int
+	buckets    []bucket
+	mask       uint64
+	seed       maphash.Seed
+	cacheFor   time.Duration
+	clock      clock.Clock
+	closed     atomic.Bool
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	expirations atomic.Uint64
+	evictions   atomic.Uint64
+}
+
+func (c *Cache) bucket(k string) (*bucket, uint64) {
+	h := maphash.String(c.seed, k)
+	return &c.buckets[h&c.mask], h
+}
+
+// Set sets a copy of v to the cache, cached for the cacheFor passed to New.
+//
+// Entries larger than the arena of a bucket, which is at least 64KiB, are not
+// stored, and the old value of k is evicted instead. See MaxEntrySize.
+func (c *Cache) Set(k string, v []byte) {
+	c.SetWithTTL(k, v, c.cacheFor)
+}
+
+// SetWithTTL sets a copy of v to the cache that will be cached for ttl instead
+// of the cacheFor passed to New. Set ttl to 0 to cache v until it is
+// overwritten by newer entries.
+func (c *Cache) SetWithTTL(k string, v []byte, ttl time.Duration) {
+	var exp int64
+	if ttl > 0 {
+		exp = c.now() + int64(ttl)
+	}
+
+	b, h := c.bucket(k)
+	c.sets.Add(1)
+	if evicted := b.set(h, k, v, exp); evicted > 0 {
+		c.evictions.Add(uint64(evicted))
+	}
+}
+
+// Get returns a copy of the value of k in the cache and if it was found or
+// not.
+//
+// Expired entries are reported as not found and deleted from the cache.
+func (c *Cache) Get(k string) ([]byte, bool) {
+	return c.AppendGet(nil, k)
+}
+
+// AppendGet is like Get, but appends the value of k to dst instead of
+// allocating a new slice for it.
+func (c *Cache) AppendGet(dst []byte, k string) ([]byte, bool) {
+	b, h := c.bucket(k)
+	v, res := b.get(dst, h, k, c.now())
+	switch res {
+	case found:
+		c.hits.Add(1)
+		return v, true
+	case expired:
+		if b.deleteExpired(h, k, c.now()) {
+			c.expirations.Add(1)
+		}
+	}
+	c.misses.Add(1)
+	return dst, false
+}
+
+// Contains reports whether k is present in the cache.
+func (c *Cache) Contains(k string) bool {
+	b, h := c.bucket(k)
+	_, res := b.get(nil, h, k, c.now())
+	return res == found
+}
+
+// Delete deletes an entry from the cache.
+func (c *Cache) Delete(k string) {
+	b, h := c.bucket(k)
+	if b.delete(h, k) {
+		c.deletes.Add(1)
+	}
+}
+
+// Wipe deletes all entries from the cache.
+func (c *Cache) Wipe() {
+	for i := range c.buckets {
+		c.deletes.Add(uint64(c.buckets[i].wipe()))
+	}
+}
+
+// Len returns the number of entries in the cache.
+//
+// Entries that expired or that were overwritten by newer ones may be counted
+// until they are read or until their bucket wraps around again.
+func (c *Cache) Len() int {
+	var n int
+	for i := range c.buckets {
+		n += c.buckets[i].len()
+	}
+	return n
+}
+
+// MaxEntrySize is the size of the largest entry the cache can hold, counting
+// its key and value.
+func (c *Cache) MaxEntrySize() int {
+	return c.bucketSize - headerSize
+}
+
+// Stats returns the statistics of the cache since it was created or since the
+// last call to ResetStats. Evictions counts the entries overwritten by newer
+// ones, and Weight the bytes reserved for the cache.
+func (c *Cache) Stats() cache.Stats {
+	return cache.Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Sets:        c.sets.Load(),
+		Deletes:     c.deletes.Load(),
+		Expirations: c.expirations.Load(),
+		Evictions:   c.evictions.Load(),
+		Size:        c.Len(),
+		Weight:      int64(len(c.buckets) * c.bucketSize),
+	}
+}
+
+// ResetStats sets all the counters returned by Stats to 0.
+func (c *Cache) ResetStats() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.sets.Store(0)
+	c.deletes.Store(0)
+	c.expirations.Store(0)
+	c.evictions.Store(0)
+}
+
+// Close releases the memory of the cache. It is safe to call Close multiple
+// times.
+//
+// A closed cache holds no entries: Set does nothing and Get reports every key
+// as not found.
+func (c *Cache) Close() {
+	if c.closed.Swap(true) {
+		return
+	}
+	for i := range c.buckets {
+		c.buckets[i].close()
+	}
+	freeArena(c.arena)
+}
+
+func (c *Cache) now() int64 {
+	return c.clock.Now().UnixNano()
+}
@@ -0,0 +1,277 @@
+package bytecache
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+func TestCache(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		maxBytes int
+		cacheFor time.Duration
+		f        func(*Cache, *clock.Fake) error
+	}{
+		{
+			"get set delete",
+			1 << 20,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				v := []byte("bar")
+				c.Set("foo", v)
+				v[0] = 'x'
+
+				got, ok := c.Get("foo")
+				if !ok || string(got) != "bar" {
+					return fmt.Errorf("expected bar but got %q, %t instead", got, ok)
+				}
+
+				c.Set("foo", []byte("baz"))
+				if got, ok := c.Get("foo"); !ok || string(got) != "baz" {
+					return fmt.Errorf("expected baz but got %q, %t instead", got, ok)
+				}
+
+				c.Delete("foo")
+				if c.Contains("foo") {
+					return fmt.Errorf("key foo found in cache")
+				}
+
+				if c.Len() != 0 {
+					return fmt.Errorf("cache len is not 0")
+				}
+
+				return nil
+			},
+		},
+		{
+			"empty values",
+			1 << 20,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				c.Set("", nil)
+				if got, ok := c.Get(""); !ok || len(got) != 0 {
+					return fmt.Errorf("expected empty value but got %q, %t instead", got, ok)
+				}
+				return nil
+			},
+		},
+		{
+			"append get",
+			1 << 20,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				c.Set("foo", []byte("bar"))
+				got, ok := c.AppendGet([]byte("foo="), "foo")
+				if !ok || string(got) != "foo=bar" {
+					return fmt.Errorf("expected foo=bar but got %q, %t instead", got, ok)
+				}
+
+				got, ok = c.AppendGet([]byte("baz="), "baz")
+				if ok || string(got) != "baz=" {
+					return fmt.Errorf("expected baz= but got %q, %t instead", got, ok)
+				}
+
+				return nil
+			},
+		},
+		{
+			"ttl",
+			1 << 20,
+			time.Minute,
+			func(c *Cache, clk *clock.Fake) error {
+				c.Set("foo", []byte("1"))
+				c.SetWithTTL("bar", []byte("2"), time.Hour)
+				c.SetWithTTL("baz", []byte("3"), 0)
+
+				clk.Advance(2 * time.Minute)
+				if c.Contains("foo") {
+					return fmt.Errorf("key foo found in cache")
+				}
+				if _, ok := c.Get("foo"); ok {
+					return fmt.Errorf("key foo found in cache")
+				}
+				if !c.Contains("bar") || !c.Contains("baz") {
+					return fmt.Errorf("keys bar and baz not found in cache")
+				}
+
+				clk.Advance(time.Hour)
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in cache")
+				}
+				if !c.Contains("baz") {
+					return fmt.Errorf("key baz not found in cache")
+				}
+
+				if s := c.Stats(); s.Expirations != 1 || c.Len() != 2 {
+					return fmt.Errorf("expected 1 expiration and 2 entries but got %d and %d instead", s.Expirations, c.Len())
+				}
+
+				return nil
+			},
+		},
+		{
+			"overwrites oldest entries",
+			0,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				v := bytes.Repeat([]byte{'x'}, 1000)
+				const n = 1000
+				for i := 0; i < n; i++ {
+					c.Set(strconv.Itoa(i), v)
+				}
+
+				if c.Contains("0") {
+					return fmt.Errorf("oldest key found in cache")
+				}
+
+				for i := n - 50; i < n; i++ {
+					if got, ok := c.Get(strconv.Itoa(i)); !ok || !bytes.Equal(got, v) {
+						return fmt.Errorf("key %d not found in cache", i)
+					}
+				}
+
+				fits := minBucketSize / (headerSize + 3 + len(v))
+				if c.Len() > 2*fits {
+					return fmt.Errorf("expected at most %d entries but got %d instead", 2*fits, c.Len())
+				}
+
+				if c.Stats().Evictions == 0 {
+					return fmt.Errorf("no evictions counted")
+				}
+
+				return nil
+			},
+		},
+		{
+			"too large",
+			0,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				c.Set("foo", []byte("old"))
+				c.Set("foo", make([]byte, c.MaxEntrySize()-2))
+				if got, ok := c.Get("foo"); ok {
+					return fmt.Errorf("expected foo to be evicted but got %q", got)
+				}
+
+				if s := c.Stats(); s.Evictions != 1 {
+					return fmt.Errorf("expected 1 eviction but got %d instead", s.Evictions)
+				}
+
+				c.Set("foo", make([]byte, c.MaxEntrySize()-3))
+				if !c.Contains("foo") {
+					return fmt.Errorf("largest entry not found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"wipe",
+			1 << 20,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				for i := 0; i < 100; i++ {
+					c.Set(strconv.Itoa(i), []byte("x"))
+				}
+				c.Wipe()
+				if c.Len() != 0 || c.Contains("1") {
+					return fmt.Errorf("cache was not wiped")
+				}
+
+				c.Set("foo", []byte("bar"))
+				if !c.Contains("foo") {
+					return fmt.Errorf("key foo not found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"close",
+			1 << 20,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				c.Set("foo", []byte("bar"))
+				c.Close()
+				c.Close()
+
+				if c.Contains("foo") {
+					return fmt.Errorf("key foo found in closed cache")
+				}
+
+				c.Set("foo", []byte("bar"))
+				c.Delete("foo")
+				c.Wipe()
+				if _, ok := c.Get("foo"); ok {
+					return fmt.Errorf("key foo found in closed cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"concurrent reads and writes",
+			1 << 20,
+			0,
+			func(c *Cache, _ *clock.Fake) error {
+				wg := new(sync.WaitGroup)
+				wg.Add(200)
+				for i := 0; i < 100; i++ {
+					go func(i int) {
+						defer wg.Done()
+						c.Set(strconv.Itoa(i), []byte(strconv.Itoa(i)))
+					}(i)
+
+					go func(i int) {
+						defer wg.Done()
+						if v, ok := c.Get(strconv.Itoa(i)); ok && string(v) != strconv.Itoa(i) {
+							panic("wrong value")
+						}
+					}(i)
+				}
+				wg.Wait()
+				return nil
+			},
+		},
+	} {
+		clk := clock.NewFake(time.Now())
+		c := New(tc.maxBytes, tc.cacheFor, WithClock(clk))
+		if err := tc.f(c, clk); err != nil {
+			t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+		}
+		c.Close()
+	}
+}
+
+func Benchmark_Set(b *testing.B) {
+	c := New(64<<20, 0)
+	defer c.Close()
+	v := make([]byte, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(strconv.Itoa(i&0xffff), v)
+	}
+}
+
+func Benchmark_Get(b *testing.B) {
+	c := New(64<<20, 0)
+	defer c.Close()
+	v := make([]byte, 100)
+	for i := 0; i < 1<<16; i++ {
+		c.Set(strconv.Itoa(i), v)
+	}
+
+	dst := make([]byte, 0, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.AppendGet(dst[:0], strconv.Itoa(i&0xffff))
+	}
+}
@@ -1,48 +1,367 @@
 package cache
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
 )
 
 // New creates a new Cache.
 // cacheFor is the amount of time the value will be cached, set to 0 to cache
 // forever. cacheFor is also the tick period of the GC. The caller can manually
 // call the GC at any time using the TickGC method.
-func New[K comparable, V any](cacheFor time.Duration) *Cache[K, V] {
-	c := &Cache[K, V]{cache: make(map[K]item[V]), cacheFor: cacheFor}
+//
+// Entries set with SetWithTTL may use a shorter lifetime than cacheFor, in
+// which case the GC will tick when the next entry expires, but no more than
+// once a second, and then go back to ticking every cacheFor.
+// Use WithGCInterval to tick the GC at a fixed period instead, or to disable it.
+//
+// Expired entries are never returned by the cache, even if the GC did not
+// delete them yet.
+//
+// The cache can be further configured with opts.
+func New[K comparable, V any](cacheFor time.Duration, opts ...Option[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		cache:    make(map[K]*item[K, V]),
+		cacheFor: cacheFor,
+		clock:    clock.Real{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.capacity <= 0 && c.maxWeight <= 0 {
+		c.policy = nil
+	} else if c.policy == nil {
+		c.policy = NewLRU[K]()
+	}
 	if cacheFor > 0 {
-		go c.gc()
+		c.startGC(cacheFor)
+	}
+	if c.bus != nil {
+		c.busID = busID()
+		c.unsubscribe = c.bus.Subscribe(c.invalidate)
 	}
 	return c
 }
 
+// NewContext is like New, but the returned cache will be closed when ctx is
+// done. See Close.
+func NewContext[K comparable, V any](ctx context.Context, cacheFor time.Duration, opts ...Option[K, V]) *Cache[K, V] {
+	c := New(cacheFor, opts...)
+	c.locker.Lock()
+	c.stopCtx = context.AfterFunc(ctx, c.Close)
+	c.locker.Unlock()
+	return c
+}
+
 // Cache is a simple Key/Value thread safe cache.
 type Cache[K comparable, V any] struct {
 	locker   sync.RWMutex
-	cache    map[K]item[V]
+	cache    map[K]*item[K, V]
 	cacheFor time.Duration
+	expiry   expiryQueue[K, V]
+
+	clock   clock.Clock
+	ticker  clock.Ticker
+	gcEvery time.Duration
+	gcFixed bool
+	done    chan struct{}
+	closed  bool
+	stopCtx func() bool
+
+	capacity  int
+	maxWeight int64
+	weight    int64
+	weigher   Weigher[K, V]
+	policy    Policy[K]
+	stats     stats
+
+	sliding bool
+	maxAge  time.Duration
+
+	refreshAfter time.Duration
+	refreshLoad  func(K) (V, error)
+
+	onEvict  func(K, V, EvictReason)
+	onExpire func(K, V)
+	pending  []eviction[K, V]
+
+	loadMu   sync.Mutex
+	calls    map[K]*call[V]
+	loadErrs map[K]loadErr
+	errTTL   time.Duration
+
+	tags map[string]map[K]struct{}
+	keys keyIndex[K]
+
+	bus         Bus
+	busName     string
+	busID       string
+	busCodec    Codec
+	unsubscribe func()
 }
 
-type item[V any] struct {
-	v    V
+type item[K comparable, V any] struct {
+	k K
+	v V
+	// nsec is when the item was set or, with sliding expiration, last read.
 	nsec int64
+	// born is when the item was set.
+	born int64
+	ttl  time.Duration
+
+	weight int64
+	tags   []string
+
+	// exp is when the item expires, or 0 if it never does.
+	exp int64
+	// index is the index of the item in the expiry queue, or -1 if it is not
+	// in the queue.
+	index int
+}
+
+// expired reports whether the item has outlived its ttl at now.
+// Items with a ttl of 0 never expire.
+func (it *item[K, V]) expired(now int64) bool {
+	return it.exp != 0 && now > it.exp
 }
 
 // Set sets a new value to the Cache cache.
 func (c *Cache[K, V]) Set(k K, v V) {
+	c.SetWithTTL(k, v, c.cacheFor)
+}
+
+// SetWithTTL sets a new value to the Cache cache that will be cached for ttl
+// instead of the cacheFor passed to New. Set ttl to 0 to cache v forever.
+func (c *Cache[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
 	c.locker.Lock()
-	defer c.locker.Unlock()
-	c.cache[k] = item[V]{v, time.Now().UnixNano()}
+	defer c.unlockAndNotify()
+	c.set(k, v, ttl)
+}
+
+// SetWithWeight sets a new value to the Cache cache with the passed weight,
+// instead of the one computed by the Weigher of the cache. Negative weights
+// are treated as 0. See WithMaxWeight.
+func (c *Cache[K, V]) SetWithWeight(k K, v V, weight int64) {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	c.setWeighted(k, v, c.cacheFor, weight)
+}
+
+// set must be called with c.locker held.
+func (c *Cache[K, V]) set(k K, v V, ttl time.Duration) {
+	weight := int64(1)
+	if c.weigher != nil {
+		weight = c.weigher(k, v)
+	}
+	c.setWeighted(k, v, ttl, weight)
+}
+
+// setWeighted must be called with c.locker held.
+func (c *Cache[K, V]) setWeighted(k K, v V, ttl time.Duration, weight int64) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	if weight < 0 {
+		weight = 0
+	}
+
+	c.stats.sets.Add(1)
+	if c.maxWeight > 0 && weight > c.maxWeight {
+		// v would evict every other entry and still not fit, so the old
+		// value of k is evicted instead.
+		c.delete(k, ReasonCapacity)
+		return
+	}
+
+	it, ok := c.cache[k]
+	if !ok {
+		it = &item[K, V]{k: k, index: -1}
+		c.cache[k] = it
+		if c.keys != nil {
+			c.keys.insert(k)
+		}
+	} else {
+		if len(it.tags) > 0 {
+			c.untag(it)
+		}
+		if c.onEvict != nil && !sameValue(it.v, v) {
+			c.pending = append(c.pending, eviction[K, V]{k, it.v, ReasonReplaced})
+		}
+	}
+
+	now := c.now()
+	c.weight += weight - it.weight
+	it.v, it.nsec, it.born, it.ttl, it.weight = v, now, now, ttl, weight
+	c.updateExp(it)
+	if it.exp != 0 {
+		c.startGC(time.Duration(it.exp - now))
+	}
+
+	if c.policy == nil {
+		return
+	}
+
+	c.policy.Add(k)
+	for c.full() {
+		victim, ok := c.policy.Evict()
+		if !ok {
+			break
+		}
+		c.remove(victim, ReasonCapacity)
+	}
+}
+
+// full reports whether the cache holds more entries or weight than allowed.
+//
+// full must be called with c.locker held.
+func (c *Cache[K, V]) full() bool {
+	return (c.capacity > 0 && len(c.cache) > c.capacity) ||
+		(c.maxWeight > 0 && c.weight > c.maxWeight)
 }
 
 // Get returns the value in the Cache cache of
 // the passed key and if it was found or not.
+//
+// Expired entries are reported as not found and deleted from the cache, even
+// if the GC did not tick yet.
 func (c *Cache[K, V]) Get(k K) (V, bool) {
+	if c.policy != nil || c.sliding {
+		c.locker.Lock()
+		defer c.unlockAndNotify()
+		v, ok := c.get(k)
+		c.stats.hit(ok)
+		return v, ok
+	}
+
+	var (
+		v       V
+		expired bool
+	)
 	c.locker.RLock()
-	defer c.locker.RUnlock()
 	item, ok := c.cache[k]
-	return item.v, ok
+	if ok {
+		now := c.now()
+		v, expired = item.v, item.expired(now)
+		if !expired {
+			c.refreshIfStale(item, now)
+		}
+	}
+	c.locker.RUnlock()
+
+	if expired {
+		c.deleteExpired(k)
+		c.stats.hit(false)
+		var zero V
+		return zero, false
+	}
+
+	c.stats.hit(ok)
+	return v, ok
+}
+
+// getWithTTL is like Get, but also returns how long until the entry expires, or
+// 0 if it never does.
+func (c *Cache[K, V]) getWithTTL(k K) (V, time.Duration, bool) {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	v, ok := c.get(k)
+	c.stats.hit(ok)
+	if !ok {
+		return v, 0, false
+	}
+
+	var ttl time.Duration
+	if exp := c.cache[k].exp; exp != 0 {
+		ttl = max(time.Duration(exp-c.now()), 1)
+	}
+	return v, ttl, true
+}
+
+// get must be called with c.locker held for writing.
+func (c *Cache[K, V]) get(k K) (V, bool) {
+	item, ok := c.cache[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	now := c.now()
+	if item.expired(now) {
+		c.delete(k, ReasonExpired)
+		var zero V
+		return zero, false
+	}
+
+	c.refreshIfStale(item, now)
+	if c.policy != nil {
+		c.policy.Access(k)
+	}
+	if c.sliding {
+		item.nsec = now
+		c.updateExp(item)
+	}
+	return item.v, true
+}
+
+// updateExp sets when it expires, counting its ttl from it.nsec and capping
+// it to the max age of the cache, if any.
+//
+// updateExp must be called with c.locker held for writing.
+func (c *Cache[K, V]) updateExp(it *item[K, V]) {
+	it.exp = 0
+	if it.ttl > 0 {
+		it.exp = it.nsec + int64(it.ttl)
+	}
+	if c.maxAge > 0 {
+		if maxExp := it.born + int64(c.maxAge); it.exp == 0 || maxExp < it.exp {
+			it.exp = maxExp
+		}
+	}
+	c.expiry.update(it)
+}
+
+// deleteExpired deletes k from the cache if it is still expired.
+func (c *Cache[K, V]) deleteExpired(k K) {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	if item, ok := c.cache[k]; ok && item.expired(c.now()) {
+		c.delete(k, ReasonExpired)
+	}
+}
+
+// delete must be called with c.locker held for writing.
+func (c *Cache[K, V]) delete(k K, reason EvictReason) {
+	if c.policy != nil {
+		c.policy.Remove(k)
+	}
+	c.remove(k, reason)
+}
+
+// remove removes k from the map of the cache, without updating the policy,
+// and queues the eviction callbacks.
+//
+// remove must be called with c.locker held for writing.
+func (c *Cache[K, V]) remove(k K, reason EvictReason) {
+	item, ok := c.cache[k]
+	if !ok {
+		return
+	}
+	delete(c.cache, k)
+	if c.keys != nil {
+		c.keys.remove(k)
+	}
+	if len(item.tags) > 0 {
+		c.untag(item)
+	}
+	c.weight -= item.weight
+	c.expiry.remove(item)
+	c.stats.removed(reason)
+	if c.onEvict != nil || c.onExpire != nil {
+		c.pending = append(c.pending, eviction[K, V]{k, item.v, reason})
+	}
 }
 
 // Contains reports whether k is present in the cache.
@@ -52,58 +371,246 @@ func (c *Cache[K, V]) Contains(k K) bool {
 }
 
 // Delete deletes an entry from the Cache cache.
+// The error cached for k by GetOrLoad, if any, is also deleted.
+//
+// If the cache uses an invalidation Bus, k is also deleted from the other
+// caches on the bus.
 func (c *Cache[K, V]) Delete(k K) {
+	c.deleteLocal(k)
+	c.publishDelete(k)
+}
+
+func (c *Cache[K, V]) deleteLocal(k K) {
+	c.forgetLoadErr(k)
 	c.locker.Lock()
-	defer c.locker.Unlock()
-	delete(c.cache, k)
+	defer c.unlockAndNotify()
+	c.delete(k, ReasonDeleted)
 }
 
 // GetSet tries to find k in the cache.
-// If found, GetSet will return the value found.
+// If found and not expired, GetSet will return the value found.
 // If not found, GetSet will return the passed value and set it to the cache.
 func (c *Cache[K, V]) GetSet(k K, v V) V {
+	return c.GetSetWithTTL(k, v, c.cacheFor)
+}
+
+// GetSetWithTTL is like GetSet, but if v is set it will be cached for ttl
+// instead of the cacheFor passed to New.
+func (c *Cache[K, V]) GetSetWithTTL(k K, v V, ttl time.Duration) V {
 	c.locker.Lock()
-	defer c.locker.Unlock()
-	val, ok := c.cache[k]
+	defer c.unlockAndNotify()
+	val, ok := c.get(k)
+	c.stats.hit(ok)
 	if ok {
-		return val.v
+		return val
 	}
-	c.cache[k] = item[V]{v, time.Now().UnixNano()}
+	c.set(k, v, ttl)
 	return v
 }
 
 // Wipe deletes all entries from the cache.
+// The errors cached by GetOrLoad are also deleted.
+//
+// If the cache uses an invalidation Bus, the other caches on the bus are also
+// wiped.
 func (c *Cache[K, V]) Wipe() {
+	c.wipeLocal()
+	c.publish(Invalidation{All: true})
+}
+
+func (c *Cache[K, V]) wipeLocal() {
+	c.forgetLoadErrs()
 	c.locker.Lock()
-	defer c.locker.Unlock()
-	c.cache = make(map[K]item[V])
+	defer c.unlockAndNotify()
+	if c.policy != nil || c.onEvict != nil || c.onExpire != nil {
+		for k := range c.cache {
+			c.delete(k, ReasonWiped)
+		}
+	} else {
+		c.stats.deletes.Add(uint64(len(c.cache)))
+	}
+	c.cache = make(map[K]*item[K, V])
+	c.weight = 0
+	c.expiry = nil
+	c.tags = nil
+	if c.keys != nil {
+		c.keys.reset()
+	}
 }
 
 // Len returns the len of the cache.
+// Expired entries that were not yet deleted by the GC are counted.
 func (c *Cache[K, V]) Len() int {
 	c.locker.RLock()
 	defer c.locker.RUnlock()
 	return len(c.cache)
 }
 
+// Evictions returns how many entries were evicted from the cache because it
+// was full. See WithCapacity and Stats.
+func (c *Cache[K, V]) Evictions() uint64 {
+	return c.stats.evictions.Load()
+}
+
 // TickGC runs the GC now.
 // It will delete all expired entries
 // from the cache.
+//
+// Entries are kept sorted by their expiration, so the GC only looks at the
+// entries that expired. They are deleted in batches, unlocking the cache in
+// between, so that the GC never blocks other goroutines for long.
 func (c *Cache[K, V]) TickGC() {
+	start := c.clock.Now()
+	for c.sweep(gcBatchSize) == gcBatchSize {
+	}
+	c.pruneLoadErrs()
+	c.stats.gc(c.clock.Now().Sub(start))
+}
+
+// sweep deletes up to n expired entries and returns how many were deleted.
+func (c *Cache[K, V]) sweep(n int) int {
 	c.locker.Lock()
-	for k, v := range c.cache {
-		if time.Since(time.Unix(0, v.nsec)) > c.cacheFor {
-			delete(c.cache, k)
+	defer c.unlockAndNotify()
+	now := c.now()
+	var deleted int
+	for deleted < n {
+		it := c.expiry.peek()
+		if it == nil || !it.expired(now) {
+			break
 		}
+		c.delete(it.k, ReasonExpired)
+		deleted++
 	}
-	c.locker.Unlock()
+	return deleted
 }
 
-func (c *Cache[K, V]) gc() {
-	ticker := time.NewTicker(c.cacheFor)
-	for range ticker.C {
-		c.TickGC()
+// minGCInterval is the shortest period the GC ticks at to delete entries with
+// a ttl shorter than cacheFor, so that tiny ttls do not make it spin.
+const minGCInterval = time.Second
+
+// startGC starts the GC goroutine, ticking when the next entry expires, after
+// the passed duration. If the GC is already running with a longer period, it
+// will be shortened.
+//
+// If the period of the GC was set with WithGCInterval, after is ignored.
+//
+// startGC does nothing if the cache is closed or if the GC is disabled.
+//
+// startGC must be called with c.locker held.
+func (c *Cache[K, V]) startGC(after time.Duration) {
+	if c.closed {
+		return
+	}
+
+	every := c.gcPeriod(after)
+	if c.gcFixed {
+		if c.gcEvery <= 0 || c.ticker != nil {
+			return
+		}
+		every = c.gcEvery
 	}
+
+	if c.ticker == nil {
+		c.ticker = c.clock.NewTicker(every)
+		c.gcEvery = every
+		c.done = make(chan struct{})
+		go c.gc(c.ticker, c.done)
+		return
+	}
+
+	if every < c.gcEvery {
+		c.gcEvery = every
+		c.ticker.Reset(every)
+	}
+}
+
+// gcPeriod returns the period the GC should tick at for the next entry to
+// expire after the passed duration: after, but no shorter than minGCInterval
+// and no longer than cacheFor.
+func (c *Cache[K, V]) gcPeriod(after time.Duration) time.Duration {
+	every := max(after, minGCInterval)
+	if c.cacheFor > 0 {
+		every = min(every, c.cacheFor)
+	}
+	return every
+}
+
+// rescheduleGC makes the GC tick when the next entry expires, or every
+// cacheFor if that is sooner. If no entry can expire and cacheFor is 0, the GC
+// is stopped until startGC is called again.
+//
+// rescheduleGC reports whether the GC goroutine of ticker should keep running.
+func (c *Cache[K, V]) rescheduleGC(ticker clock.Ticker) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if c.closed || c.ticker != ticker {
+		return false
+	}
+	if c.gcFixed {
+		return true
+	}
+
+	next := c.expiry.peek()
+	if next == nil && c.cacheFor <= 0 {
+		c.ticker.Stop()
+		c.ticker, c.done = nil, nil
+		return false
+	}
+
+	every := c.cacheFor
+	if next != nil {
+		every = c.gcPeriod(time.Duration(next.exp - c.now()))
+	}
+	if every != c.gcEvery {
+		c.gcEvery = every
+		c.ticker.Reset(every)
+	}
+	return true
+}
+
+func (c *Cache[K, V]) gc(ticker clock.Ticker, done <-chan struct{}) {
+	for {
+		select {
+		case <-ticker.C():
+			c.TickGC()
+			if !c.rescheduleGC(ticker) {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Close stops the GC of the cache, releasing its goroutine and ticker, and
+// unsubscribes the cache from its invalidation Bus, if any.
+// It is safe to call Close multiple times.
+//
+// A closed cache can still be used, but expired entries will only be deleted
+// when they are read or when TickGC is called.
+func (c *Cache[K, V]) Close() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	if c.stopCtx != nil {
+		c.stopCtx()
+	}
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+	}
+	if c.ticker != nil {
+		c.ticker.Stop()
+		close(c.done)
+	}
+}
+
+// now returns the time of the clock of the cache, in nanoseconds.
+func (c *Cache[K, V]) now() int64 {
+	return c.clock.Now().UnixNano()
 }
 
 // Lock locks rw for writing. If the lock is already locked for reading or
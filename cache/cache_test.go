@@ -1,23 +1,50 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
 )
 
+// setExpired sets k to v as if it had expired an hour ago, without waiting for
+// the GC to tick.
+func setExpired[K comparable, V any](c *Cache[K, V], k K, v V) {
+	c.SetWithTTL(k, v, time.Hour)
+	c.locker.Lock()
+	it := c.cache[k]
+	it.nsec -= int64(2 * time.Hour)
+	it.exp -= int64(2 * time.Hour)
+	c.expiry.update(it)
+	c.locker.Unlock()
+}
+
+// waitFor polls cond until it returns true or a second passes.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
 func TestCache(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
 		cacheFor time.Duration
-		f        func(*Cache[string, any]) error
+		f        func(*Cache[string, any], *clock.Fake) error
 	}{
 		{
 			"get set delete",
 			0,
-			func(c *Cache[string, any]) error {
+			func(c *Cache[string, any], clk *clock.Fake) error {
 				c.Set("foo", 1)
 				v, ok := c.Get("foo")
 				if !ok {
@@ -42,7 +69,7 @@ func TestCache(t *testing.T) {
 		{
 			"concurrent reads and writes",
 			0,
-			func(c *Cache[string, any]) error {
+			func(c *Cache[string, any], clk *clock.Fake) error {
 				wg := new(sync.WaitGroup)
 				wg.Add(200)
 				for i := 0; i < 100; i++ {
@@ -63,20 +90,119 @@ func TestCache(t *testing.T) {
 		{
 			"gc",
 			25 * time.Millisecond,
-			func(c *Cache[string, any]) error {
+			func(c *Cache[string, any], clk *clock.Fake) error {
 				c.Set("foo", 1)
-				time.Sleep(60 * time.Millisecond)
-				_, ok := c.Get("foo")
-				if ok {
+				clk.Advance(30 * time.Millisecond)
+				if !waitFor(func() bool { return c.Len() == 0 }) {
+					return fmt.Errorf("key foo was not deleted by the GC")
+				}
+				return nil
+			},
+		},
+		{
+			"set with ttl",
+			0,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.SetWithTTL("foo", 1, 25*time.Millisecond)
+				c.Set("bar", 2)
+				clk.Advance(30 * time.Millisecond)
+				if _, ok := c.Get("foo"); ok {
+					return fmt.Errorf("key foo found in Cache")
+				}
+				if _, ok := c.Get("bar"); !ok {
+					return fmt.Errorf("key bar not found in Cache")
+				}
+				return nil
+			},
+		},
+		{
+			"set with ttl forever",
+			25 * time.Millisecond,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.SetWithTTL("foo", 1, 0)
+				c.Set("bar", 2)
+				clk.Advance(30 * time.Millisecond)
+				if _, ok := c.Get("foo"); !ok {
+					return fmt.Errorf("key foo not found in Cache")
+				}
+				if _, ok := c.Get("bar"); ok {
+					return fmt.Errorf("key bar found in Cache")
+				}
+				return nil
+			},
+		},
+		{
+			"get set with ttl",
+			0,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				if v := c.GetSetWithTTL("foo", 1, 25*time.Millisecond); v != 1 {
+					return fmt.Errorf("expected foo value to be 1 but got %v instead", v)
+				}
+				if v := c.GetSetWithTTL("foo", 2, 25*time.Millisecond); v != 1 {
+					return fmt.Errorf("expected foo value to be 1 but got %v instead", v)
+				}
+				clk.Advance(30 * time.Millisecond)
+				if _, ok := c.Get("foo"); ok {
 					return fmt.Errorf("key foo found in Cache")
 				}
 				return nil
 			},
 		},
+		{
+			"lazy expiry",
+			time.Hour,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				setExpired(c, "foo", 1)
+				setExpired(c, "bar", 2)
+
+				if _, ok := c.Get("foo"); ok {
+					return fmt.Errorf("key foo found in Cache")
+				}
+
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in Cache")
+				}
+
+				if c.Len() != 0 {
+					return fmt.Errorf("cache len is not 0")
+				}
+
+				setExpired(c, "foo", 1)
+				if v := c.GetSet("foo", 2); v != 2 {
+					return fmt.Errorf("expected foo value to be 2 but got %v instead", v)
+				}
+
+				return nil
+			},
+		},
+		{
+			"incremental gc",
+			time.Hour,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				for i := 0; i < 3*gcBatchSize; i++ {
+					setExpired[string, any](c, strconv.Itoa(i), i)
+				}
+				c.SetWithTTL("foo", 1, time.Hour)
+				c.SetWithTTL("bar", 2, time.Hour)
+				c.SetWithTTL("bar", 2, 0)
+
+				c.TickGC()
+
+				if c.Len() != 2 {
+					return fmt.Errorf("cache len is not 2, it is %d", c.Len())
+				}
+
+				if len(c.expiry) != 1 || c.expiry.peek().k != "foo" {
+					return fmt.Errorf("expected only foo in the expiry queue")
+				}
+
+				return nil
+			},
+		},
 		{
 			"get set",
 			0,
-			func(c *Cache[string, any]) error {
+			func(c *Cache[string, any], clk *clock.Fake) error {
 				i := new(int)
 				i2 := new(int)
 
@@ -104,7 +230,7 @@ func TestCache(t *testing.T) {
 		{
 			"wipe and len",
 			0,
-			func(c *Cache[string, any]) error {
+			func(c *Cache[string, any], clk *clock.Fake) error {
 				c.Set("foo", 1)
 				c.Set("bar", 2)
 
@@ -124,7 +250,7 @@ func TestCache(t *testing.T) {
 		{
 			"contains",
 			0,
-			func(c *Cache[string, any]) error {
+			func(c *Cache[string, any], clk *clock.Fake) error {
 				ok := c.Contains("foo")
 				if ok {
 					return fmt.Errorf("key foo found in cache")
@@ -142,7 +268,613 @@ func TestCache(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			c := New[string, any](tc.cacheFor)
+			clk := clock.NewFake(time.Now())
+			c := New(tc.cacheFor, WithClock[string, any](clk))
+			defer c.Close()
+			if err := tc.f(c, clk); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestCacheGCSchedule(t *testing.T) {
+	gcEvery := func(c *Cache[string, any]) time.Duration {
+		c.RLock()
+		defer c.RUnlock()
+		return c.gcEvery
+	}
+
+	for _, tc := range []struct {
+		name     string
+		cacheFor time.Duration
+		f        func(*Cache[string, any], *clock.Fake) error
+	}{
+		{
+			"short ttl",
+			time.Hour,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.SetWithTTL("foo", 1, time.Microsecond)
+				if every := gcEvery(c); every != minGCInterval {
+					return fmt.Errorf("expected the GC to tick every %v but it ticks every %v", minGCInterval, every)
+				}
+
+				clk.Advance(minGCInterval)
+				if !waitFor(func() bool { return c.Len() == 0 && gcEvery(c) == time.Hour }) {
+					return fmt.Errorf("expected the GC to delete key foo and tick every hour, but it ticks every %v", gcEvery(c))
+				}
+
+				return nil
+			},
+		},
+		{
+			"next expiry",
+			0,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.SetWithTTL("foo", 1, time.Minute)
+				c.SetWithTTL("bar", 2, time.Hour)
+				if every := gcEvery(c); every != time.Minute {
+					return fmt.Errorf("expected the GC to tick every minute but it ticks every %v", every)
+				}
+
+				clk.Advance(time.Minute + time.Second)
+				if !waitFor(func() bool { return gcEvery(c) == time.Hour-time.Minute-time.Second }) {
+					return fmt.Errorf("expected the GC to tick when key bar expires, but it ticks every %v", gcEvery(c))
+				}
+
+				return nil
+			},
+		},
+		{
+			"stops without entries to expire",
+			0,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.SetWithTTL("foo", 1, time.Minute)
+				clk.Advance(time.Minute + time.Second)
+				if !waitFor(func() bool { return clk.Tickers() == 0 }) {
+					return fmt.Errorf("GC did not stop")
+				}
+
+				if c.Len() != 0 {
+					return fmt.Errorf("key foo was not deleted by the GC")
+				}
+
+				c.SetWithTTL("foo", 1, time.Minute)
+				if clk.Tickers() != 1 {
+					return fmt.Errorf("GC did not start again")
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := New(tc.cacheFor, WithClock[string, any](clk))
+			defer c.Close()
+			if err := tc.f(c, clk); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestCacheCapacity(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    func(*Cache[string, any]) error
+	}{
+		{
+			"evicts least recently used",
+			func(c *Cache[string, any]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				c.Get("foo")
+				c.Set("baz", 3)
+
+				if c.Len() != 2 {
+					return fmt.Errorf("cache len is not 2")
+				}
+
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in cache")
+				}
+
+				if !c.Contains("foo") || !c.Contains("baz") {
+					return fmt.Errorf("keys foo and baz not found in cache")
+				}
+
+				if c.Evictions() != 1 {
+					return fmt.Errorf("expected 1 eviction but got %d instead", c.Evictions())
+				}
+
+				return nil
+			},
+		},
+		{
+			"get set counts as use",
+			func(c *Cache[string, any]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				c.GetSet("foo", 3)
+				c.Set("baz", 3)
+
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"overwrite does not evict",
+			func(c *Cache[string, any]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				c.Set("foo", 3)
+
+				if c.Len() != 2 || c.Evictions() != 0 {
+					return fmt.Errorf("overwriting foo evicted an entry")
+				}
+
+				return nil
+			},
+		},
+		{
+			"delete and wipe free space",
+			func(c *Cache[string, any]) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				c.Delete("foo")
+				c.Set("baz", 3)
+
+				if c.Evictions() != 0 {
+					return fmt.Errorf("expected 0 evictions but got %d instead", c.Evictions())
+				}
+
+				c.Wipe()
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+
+				if c.Len() != 2 || c.Evictions() != 0 {
+					return fmt.Errorf("wipe did not free the cache")
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New[string, any](0, WithCapacity[string, any](2))
+			if err := tc.f(c); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		new  func(clk *clock.Fake) (*Cache[string, any], func())
+	}{
+		{
+			"close",
+			func(clk *clock.Fake) (*Cache[string, any], func()) {
+				c := New(10*time.Millisecond, WithClock[string, any](clk))
+				return c, c.Close
+			},
+		},
+		{
+			"context",
+			func(clk *clock.Fake) (*Cache[string, any], func()) {
+				ctx, cancel := context.WithCancel(context.Background())
+				c := NewContext(ctx, 10*time.Millisecond, WithClock[string, any](clk))
+				return c, func() {
+					cancel()
+					waitFor(func() bool { return clk.Tickers() == 0 })
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c, closeCache := tc.new(clk)
+			closeCache()
+			c.Close()
+
+			if clk.Tickers() != 0 {
+				t.Errorf("\ntest '%s' failed\nerr: GC ticker was not stopped", tc.name)
+			}
+
+			c.SetWithTTL("foo", 1, time.Millisecond)
+			clk.Advance(30 * time.Millisecond)
+
+			if c.Len() != 1 {
+				t.Errorf("\ntest '%s' failed\nerr: GC ran on a closed cache", tc.name)
+			}
+
+			if c.Contains("foo") {
+				t.Errorf("\ntest '%s' failed\nerr: key foo found in cache", tc.name)
+			}
+
+			c.Set("bar", 2)
+			c.TickGC()
+			if c.Len() != 1 {
+				t.Errorf("\ntest '%s' failed\nerr: cache len is not 1", tc.name)
+			}
+		})
+	}
+}
+
+func TestCacheCallbacks(t *testing.T) {
+	type event struct {
+		k      string
+		v      any
+		reason EvictReason
+	}
+
+	for _, tc := range []struct {
+		name string
+		f    func(*Cache[string, any])
+		want []event
+	}{
+		{
+			"delete",
+			func(c *Cache[string, any]) {
+				c.Set("foo", 1)
+				c.Set("foo", 2)
+				c.Delete("foo")
+				c.Delete("bar")
+			},
+			[]event{{"foo", 1, ReasonReplaced}, {"foo", 2, ReasonDeleted}},
+		},
+		{
+			"replace with the same value",
+			func(c *Cache[string, any]) {
+				c.Set("foo", 1)
+				c.Set("foo", 1)
+				c.Set("foo", int64(1))
+			},
+			[]event{{"foo", 1, ReasonReplaced}},
+		},
+		{
+			"replace with a value that is not comparable",
+			func(c *Cache[string, any]) {
+				c.Set("foo", []int{1})
+				c.Set("foo", 1)
+			},
+			[]event{{"foo", []int{1}, ReasonReplaced}},
+		},
+		{
+			"wipe",
+			func(c *Cache[string, any]) {
+				c.Set("foo", 1)
+				c.Wipe()
+			},
+			[]event{{"foo", 1, ReasonWiped}},
+		},
+		{
+			"capacity",
+			func(c *Cache[string, any]) {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				c.Set("baz", 3)
+			},
+			[]event{{"foo", 1, ReasonCapacity}},
+		},
+		{
+			"expired on get",
+			func(c *Cache[string, any]) {
+				setExpired(c, "foo", 1)
+				c.Get("foo")
+			},
+			[]event{{"foo", 1, ReasonExpired}, {"foo", 1, ReasonExpired}},
+		},
+		{
+			"expired on gc",
+			func(c *Cache[string, any]) {
+				setExpired(c, "foo", 1)
+				c.TickGC()
+			},
+			[]event{{"foo", 1, ReasonExpired}, {"foo", 1, ReasonExpired}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []event
+			var c *Cache[string, any]
+			c = New[string, any](0,
+				WithCapacity[string, any](2),
+				WithOnEvict(func(k string, v any, reason EvictReason) {
+					// The cache must be unlocked.
+					c.Contains(k)
+					got = append(got, event{k, v, reason})
+				}),
+				WithOnExpire(func(k string, v any) {
+					got = append(got, event{k, v, ReasonExpired})
+				}),
+			)
+			defer c.Close()
+
+			tc.f(c)
+
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("\ntest '%s' failed\nwant: %v\ngot: %v", tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func Benchmark_TickGC(b *testing.B) {
+	c := New[int, int](time.Hour)
+	defer c.Close()
+	for i := 0; i < 1e5; i++ {
+		c.Set(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		setExpired(c, -1, i)
+		c.TickGC()
+	}
+}
+
+func TestCacheGCInterval(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cacheFor time.Duration
+		interval time.Duration
+		f        func(*Cache[string, any], *clock.Fake) error
+	}{
+		{
+			"fixed interval",
+			time.Hour,
+			time.Minute,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.SetWithTTL("foo", 1, time.Millisecond)
+
+				c.RLock()
+				every := c.gcEvery
+				c.RUnlock()
+				if every != time.Minute {
+					return fmt.Errorf("expected the GC to tick every minute but it ticks every %v", every)
+				}
+
+				clk.Advance(time.Minute)
+				if !waitFor(func() bool { return c.Len() == 0 }) {
+					return fmt.Errorf("key foo was not deleted by the GC")
+				}
+
+				return nil
+			},
+		},
+		{
+			"started by ttl",
+			0,
+			time.Minute,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				if clk.Tickers() != 0 {
+					return fmt.Errorf("GC started without entries to expire")
+				}
+
+				c.SetWithTTL("foo", 1, time.Hour)
+				if clk.Tickers() != 1 {
+					return fmt.Errorf("GC did not start")
+				}
+
+				return nil
+			},
+		},
+		{
+			"disabled",
+			time.Millisecond,
+			0,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.Set("foo", 1)
+				if clk.Tickers() != 0 {
+					return fmt.Errorf("GC started")
+				}
+
+				clk.Advance(time.Hour)
+				if c.Len() != 1 {
+					return fmt.Errorf("cache len is not 1")
+				}
+
+				c.TickGC()
+				if c.Len() != 0 {
+					return fmt.Errorf("TickGC did not delete key foo")
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := New(
+				tc.cacheFor,
+				WithGCInterval[string, any](tc.interval),
+				WithClock[string, any](clk),
+			)
+			defer c.Close()
+			if err := tc.f(c, clk); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestCacheSlidingExpiration(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		maxAge time.Duration
+		f      func(*Cache[string, any], *clock.Fake) error
+	}{
+		{
+			"reads refresh the ttl",
+			0,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.Set("foo", 1)
+				c.Set("bar", 2)
+				for i := 0; i < 5; i++ {
+					clk.Advance(20 * time.Millisecond)
+					if _, ok := c.Get("foo"); !ok {
+						return fmt.Errorf("key foo not found in cache after %d reads", i)
+					}
+				}
+
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in cache")
+				}
+
+				clk.Advance(100 * time.Millisecond)
+				if c.Contains("foo") {
+					return fmt.Errorf("key foo found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"max age",
+			70 * time.Millisecond,
+			func(c *Cache[string, any], clk *clock.Fake) error {
+				c.Set("foo", 1)
+				c.SetWithTTL("bar", 2, 0)
+				for i := 0; i < 2; i++ {
+					clk.Advance(20 * time.Millisecond)
+					if _, ok := c.Get("foo"); !ok {
+						return fmt.Errorf("key foo not found in cache after %d reads", i)
+					}
+				}
+
+				clk.Advance(40 * time.Millisecond)
+				if c.Contains("foo") {
+					return fmt.Errorf("key foo found in cache")
+				}
+
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in cache")
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := New(
+				50*time.Millisecond,
+				WithSlidingExpiration[string, any](tc.maxAge),
+				WithClock[string, any](clk),
+			)
+			defer c.Close()
+			if err := tc.f(c, clk); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestCacheMaxWeight(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    func(*Cache[string, string]) error
+	}{
+		{
+			"evicts by weight",
+			func(c *Cache[string, string]) error {
+				c.Set("foo", "1234")
+				c.Set("bar", "1234")
+				c.Get("foo")
+				c.Set("baz", "12")
+				c.Set("qux", "12")
+
+				if c.Contains("bar") {
+					return fmt.Errorf("key bar found in cache")
+				}
+
+				if got := c.Stats().Weight; got != 8 {
+					return fmt.Errorf("expected weight 8 but got %d instead", got)
+				}
+
+				return nil
+			},
+		},
+		{
+			"overwrite updates weight",
+			func(c *Cache[string, string]) error {
+				c.Set("foo", "12345678")
+				c.Set("foo", "1")
+
+				if got := c.Stats().Weight; got != 1 {
+					return fmt.Errorf("expected weight 1 but got %d instead", got)
+				}
+
+				c.Delete("foo")
+				if got := c.Stats().Weight; got != 0 {
+					return fmt.Errorf("expected weight 0 but got %d instead", got)
+				}
+
+				return nil
+			},
+		},
+		{
+			"set with weight",
+			func(c *Cache[string, string]) error {
+				c.Set("foo", "1")
+				c.SetWithWeight("bar", "1", 8)
+
+				if c.Contains("foo") || !c.Contains("bar") {
+					return fmt.Errorf("key foo was not evicted by bar")
+				}
+
+				return nil
+			},
+		},
+		{
+			"negative weight",
+			func(c *Cache[string, string]) error {
+				c.SetWithWeight("foo", "1", -100)
+				c.Set("bar", "12345678")
+
+				if got := c.Stats().Weight; got != 8 {
+					return fmt.Errorf("expected weight 8 but got %d instead", got)
+				}
+
+				c.Set("baz", "1")
+				if c.Contains("bar") {
+					return fmt.Errorf("max weight was not enforced")
+				}
+
+				return nil
+			},
+		},
+		{
+			"too heavy",
+			func(c *Cache[string, string]) error {
+				c.Set("foo", "1")
+				c.Set("bar", "1")
+				c.Set("bar", "123456789")
+
+				if c.Contains("bar") || !c.Contains("foo") {
+					return fmt.Errorf("heavy bar was stored")
+				}
+
+				if got := c.Stats().Weight; got != 1 {
+					return fmt.Errorf("expected weight 1 but got %d instead", got)
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(0, WithMaxWeight(8, func(_ string, v string) int64 {
+				return int64(len(v))
+			}))
+			defer c.Close()
 			if err := tc.f(c); err != nil {
 				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
 			}
@@ -0,0 +1,57 @@
+// Package clock provides the time source of the caches of package cache, so
+// that it can be replaced with a fake one in tests.
+package clock
+
+import "time"
+
+// Clock tells the time and creates tickers.
+//
+// A Clock must be safe for concurrent use.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a Ticker ticking every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, as time.Ticker does.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Reset stops the ticker and resets its period to d.
+	Reset(d time.Duration)
+
+	// Stop turns off the ticker. No more ticks will be sent after it returns.
+	Stop()
+}
+
+// Real is the Clock of the system, backed by package time.
+type Real struct{}
+
+// Now returns time.Now().
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker returns a Ticker backed by a time.Ticker.
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Reset(d time.Duration) {
+	t.t.Reset(d)
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// NewFake returns a Fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Fake is a Clock whose time only moves when Advance or Set is called.
+//
+// Its tickers tick when the time is moved past their next tick. Like those of
+// time.Ticker, their channels hold a single tick, and ticks are dropped if the
+// receiver is too slow.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// Now returns the time of the clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTicker returns a Ticker ticking every d of the time of the clock.
+// It panics if d <= 0.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{
+		f:      f,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   f.now.Add(d),
+	}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance moves the time of the clock forward by d, firing the tickers due in
+// the meantime.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(f.now.Add(d))
+}
+
+// Set sets the time of the clock to now, firing the tickers due in the
+// meantime. Setting the time backwards does not fire any ticker.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(now)
+}
+
+// Tickers returns the number of running tickers created by the clock.
+func (f *Fake) Tickers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tickers)
+}
+
+// set must be called with f.mu held.
+func (f *Fake) set(now time.Time) {
+	f.now = now
+	for _, t := range f.tickers {
+		if now.Before(t.next) {
+			continue
+		}
+
+		select {
+		case t.c <- now:
+		default:
+		}
+
+		// Collapse the ticks missed since t.next into the one just sent.
+		missed := now.Sub(t.next) / t.period
+		t.next = t.next.Add((missed + 1) * t.period)
+	}
+}
+
+type fakeTicker struct {
+	f      *Fake
+	c      chan time.Time
+	period time.Duration
+
+	// next is guarded by f.mu.
+	next time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.period = d
+	t.next = t.f.now.Add(d)
+	for _, ft := range t.f.tickers {
+		if ft == t {
+			return
+		}
+	}
+	t.f.tickers = append(t.f.tickers, t)
+}
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	for i, ft := range t.f.tickers {
+		if ft == t {
+			t.f.tickers = append(t.f.tickers[:i], t.f.tickers[i+1:]...)
+			return
+		}
+	}
+}
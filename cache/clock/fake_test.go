@@ -0,0 +1,108 @@
+package clock
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name string
+		f    func(*Fake) error
+	}{
+		{
+			"advance",
+			func(f *Fake) error {
+				f.Advance(time.Hour)
+				if got := f.Now(); !got.Equal(start.Add(time.Hour)) {
+					return fmt.Errorf("expected %v but got %v instead", start.Add(time.Hour), got)
+				}
+				return nil
+			},
+		},
+		{
+			"ticks",
+			func(f *Fake) error {
+				tk := f.NewTicker(time.Second)
+				defer tk.Stop()
+
+				f.Advance(999 * time.Millisecond)
+				select {
+				case <-tk.C():
+					return fmt.Errorf("ticked early")
+				default:
+				}
+
+				f.Advance(time.Millisecond)
+				select {
+				case got := <-tk.C():
+					if !got.Equal(start.Add(time.Second)) {
+						return fmt.Errorf("expected tick at %v but got %v instead", start.Add(time.Second), got)
+					}
+				default:
+					return fmt.Errorf("did not tick")
+				}
+
+				return nil
+			},
+		},
+		{
+			"drops missed ticks",
+			func(f *Fake) error {
+				tk := f.NewTicker(time.Second)
+				defer tk.Stop()
+
+				f.Advance(10 * time.Second)
+				<-tk.C()
+				select {
+				case <-tk.C():
+					return fmt.Errorf("missed ticks were not dropped")
+				default:
+				}
+
+				f.Advance(time.Second)
+				select {
+				case <-tk.C():
+				default:
+					return fmt.Errorf("did not tick after dropping missed ticks")
+				}
+
+				return nil
+			},
+		},
+		{
+			"reset and stop",
+			func(f *Fake) error {
+				tk := f.NewTicker(time.Hour)
+				tk.Reset(time.Second)
+				f.Advance(time.Second)
+				select {
+				case <-tk.C():
+				default:
+					return fmt.Errorf("did not tick after reset")
+				}
+
+				tk.Stop()
+				if f.Tickers() != 0 {
+					return fmt.Errorf("expected no tickers but got %d instead", f.Tickers())
+				}
+
+				f.Advance(time.Hour)
+				select {
+				case <-tk.C():
+					return fmt.Errorf("ticked after stop")
+				default:
+				}
+
+				return nil
+			},
+		},
+	} {
+		if err := tc.f(NewFake(start)); err != nil {
+			t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cache
+
+// ComputeOp tells Compute what to do with the value returned by its function.
+type ComputeOp uint8
+
+const (
+	// ComputeSet sets the returned value to the cache.
+	ComputeSet ComputeOp = iota
+	// ComputeDelete deletes the key from the cache.
+	ComputeDelete
+	// ComputeCancel leaves the cache as it is.
+	ComputeCancel
+)
+
+// Compute atomically updates the value of k. f is called with the current
+// value of k and if it was found or not, and what is done with the value it
+// returns depends on the returned op.
+//
+// Compute returns the value of k after it ran and if k is in the cache.
+//
+// The value is set like Set would do, restarting its ttl. f is called with
+// the cache locked, so it must not use the cache.
+func (c *Cache[K, V]) Compute(k K, f func(old V, found bool) (V, ComputeOp)) (V, bool) {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+
+	old, found := c.get(k)
+	v, op := f(old, found)
+	switch op {
+	case ComputeSet:
+		c.set(k, v, c.cacheFor)
+		return v, true
+	case ComputeDelete:
+		if found {
+			c.delete(k, ReasonDeleted)
+		}
+		var zero V
+		return zero, false
+	default:
+		return old, found
+	}
+}
+
+// Update atomically sets the value of k to the one returned by f, if k is
+// found in the cache. It returns if k was found.
+//
+// f is called with the cache locked, so it must not use the cache.
+func (c *Cache[K, V]) Update(k K, f func(old V) V) bool {
+	_, found := c.Compute(k, func(old V, found bool) (V, ComputeOp) {
+		if !found {
+			return old, ComputeCancel
+		}
+		return f(old), ComputeSet
+	})
+	return found
+}
+
+// SetIfAbsent sets v to k only if k is not in the cache. It returns if v was
+// set.
+func (c *Cache[K, V]) SetIfAbsent(k K, v V) bool {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	if _, ok := c.get(k); ok {
+		return false
+	}
+	c.set(k, v, c.cacheFor)
+	return true
+}
+
+// CompareAndSwap sets the value of k to new if its current value is equal to
+// old. It returns if the value was swapped.
+//
+// As with sync.Map, the values must be of a comparable type, or
+// CompareAndSwap will panic.
+func (c *Cache[K, V]) CompareAndSwap(k K, old, new V) bool {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	if cur, ok := c.get(k); !ok || any(cur) != any(old) {
+		return false
+	}
+	c.set(k, new, c.cacheFor)
+	return true
+}
+
+// CompareAndDelete deletes k if its value is equal to old. It returns if k was
+// deleted.
+//
+// As with sync.Map, the values must be of a comparable type, or
+// CompareAndDelete will panic.
+func (c *Cache[K, V]) CompareAndDelete(k K, old V) bool {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	if cur, ok := c.get(k); !ok || any(cur) != any(old) {
+		return false
+	}
+	c.delete(k, ReasonDeleted)
+	return true
+}
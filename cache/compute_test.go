@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCacheCompute(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    func(*Cache[string, int]) error
+	}{
+		{
+			"compute",
+			func(c *Cache[string, int]) error {
+				inc := func(old int, found bool) (int, ComputeOp) {
+					return old + 1, ComputeSet
+				}
+
+				if v, ok := c.Compute("foo", inc); !ok || v != 1 {
+					return fmt.Errorf("expected 1, true but got %d, %t instead", v, ok)
+				}
+
+				if v, ok := c.Compute("foo", inc); !ok || v != 2 {
+					return fmt.Errorf("expected 2, true but got %d, %t instead", v, ok)
+				}
+
+				v, ok := c.Compute("foo", func(old int, found bool) (int, ComputeOp) {
+					return 10, ComputeCancel
+				})
+				if !ok || v != 2 {
+					return fmt.Errorf("expected 2, true but got %d, %t instead", v, ok)
+				}
+
+				if _, ok := c.Compute("foo", func(int, bool) (int, ComputeOp) {
+					return 0, ComputeDelete
+				}); ok || c.Contains("foo") {
+					return fmt.Errorf("key foo found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"concurrent compute",
+			func(c *Cache[string, int]) error {
+				wg := new(sync.WaitGroup)
+				wg.Add(100)
+				for i := 0; i < 100; i++ {
+					go func() {
+						defer wg.Done()
+						c.Compute("foo", func(old int, _ bool) (int, ComputeOp) {
+							return old + 1, ComputeSet
+						})
+					}()
+				}
+				wg.Wait()
+
+				if v, _ := c.Get("foo"); v != 100 {
+					return fmt.Errorf("expected 100 but got %d instead", v)
+				}
+
+				return nil
+			},
+		},
+		{
+			"update",
+			func(c *Cache[string, int]) error {
+				double := func(old int) int { return old * 2 }
+				if c.Update("foo", double) || c.Contains("foo") {
+					return fmt.Errorf("missing key foo was updated")
+				}
+
+				c.Set("foo", 2)
+				if !c.Update("foo", double) {
+					return fmt.Errorf("key foo was not updated")
+				}
+
+				if v, _ := c.Get("foo"); v != 4 {
+					return fmt.Errorf("expected 4 but got %d instead", v)
+				}
+
+				return nil
+			},
+		},
+		{
+			"set if absent",
+			func(c *Cache[string, int]) error {
+				if !c.SetIfAbsent("foo", 1) {
+					return fmt.Errorf("key foo was not set")
+				}
+
+				if c.SetIfAbsent("foo", 2) {
+					return fmt.Errorf("key foo was set twice")
+				}
+
+				if v, _ := c.Get("foo"); v != 1 {
+					return fmt.Errorf("expected 1 but got %d instead", v)
+				}
+
+				return nil
+			},
+		},
+		{
+			"compare and swap",
+			func(c *Cache[string, int]) error {
+				if c.CompareAndSwap("foo", 0, 1) {
+					return fmt.Errorf("missing key foo was swapped")
+				}
+
+				c.Set("foo", 1)
+				if c.CompareAndSwap("foo", 2, 3) {
+					return fmt.Errorf("key foo was swapped with the wrong old value")
+				}
+
+				if !c.CompareAndSwap("foo", 1, 3) {
+					return fmt.Errorf("key foo was not swapped")
+				}
+
+				if v, _ := c.Get("foo"); v != 3 {
+					return fmt.Errorf("expected 3 but got %d instead", v)
+				}
+
+				return nil
+			},
+		},
+		{
+			"compare and delete",
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+				if c.CompareAndDelete("foo", 2) {
+					return fmt.Errorf("key foo was deleted with the wrong old value")
+				}
+
+				if !c.CompareAndDelete("foo", 1) || c.Contains("foo") {
+					return fmt.Errorf("key foo was not deleted")
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New[string, int](0)
+			defer c.Close()
+			if err := tc.f(c); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+import "reflect"
+
+// EvictReason is the reason an entry was removed from a Cache.
+type EvictReason uint8
+
+const (
+	// ReasonExpired means the entry outlived its ttl.
+	ReasonExpired EvictReason = iota + 1
+	// ReasonDeleted means the entry was deleted with Delete.
+	ReasonDeleted
+	// ReasonWiped means the entry was deleted with Wipe.
+	ReasonWiped
+	// ReasonCapacity means the entry was evicted because the cache was full.
+	ReasonCapacity
+	// ReasonReplaced means the value of the entry was replaced by a different
+	// one, be it by Set or by any other method setting it.
+	ReasonReplaced
+)
+
+// String returns the name of r.
+func (r EvictReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonWiped:
+		return "wiped"
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// sameValue reports whether a and b are identical comparable values, in which
+// case replacing a with b is not reported as an eviction.
+func sameValue[V any](a, b V) bool {
+	va, vb := reflect.ValueOf(any(a)), reflect.ValueOf(any(b))
+	if !va.IsValid() || !vb.IsValid() {
+		return va.IsValid() == vb.IsValid()
+	}
+	return va.Type() == vb.Type() && va.Comparable() && va.Equal(vb)
+}
+
+type eviction[K comparable, V any] struct {
+	k      K
+	v      V
+	reason EvictReason
+}
+
+// unlockAndNotify unlocks c.locker and then calls the eviction callbacks for
+// the entries removed while it was held, so that the callbacks can safely use
+// the cache.
+func (c *Cache[K, V]) unlockAndNotify() {
+	evs := c.pending
+	c.pending = nil
+	c.locker.Unlock()
+
+	for _, ev := range evs {
+		if ev.reason == ReasonExpired && c.onExpire != nil {
+			c.onExpire(ev.k, ev.v)
+		}
+		if c.onEvict != nil {
+			c.onEvict(ev.k, ev.v, ev.reason)
+		}
+	}
+}
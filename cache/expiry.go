@@ -0,0 +1,65 @@
+package cache
+
+import "container/heap"
+
+// gcBatchSize is the maximum amount of expired entries the GC deletes before
+// unlocking the cache.
+const gcBatchSize = 1024
+
+// expiryQueue is a min heap of the items that can expire, ordered by their
+// expiration, so that the GC only has to look at the items that are due.
+type expiryQueue[K comparable, V any] []*item[K, V]
+
+func (q expiryQueue[K, V]) Len() int { return len(q) }
+
+func (q expiryQueue[K, V]) Less(i, j int) bool { return q[i].exp < q[j].exp }
+
+func (q expiryQueue[K, V]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *expiryQueue[K, V]) Push(x any) {
+	it := x.(*item[K, V])
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+
+func (q *expiryQueue[K, V]) Pop() any {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*q = old[:n-1]
+	return it
+}
+
+// update adds, moves or removes it from the queue after its expiration was
+// changed.
+func (q *expiryQueue[K, V]) update(it *item[K, V]) {
+	switch {
+	case it.exp == 0:
+		q.remove(it)
+	case it.index >= 0:
+		heap.Fix(q, it.index)
+	default:
+		heap.Push(q, it)
+	}
+}
+
+// remove removes it from the queue, if it is in it.
+func (q *expiryQueue[K, V]) remove(it *item[K, V]) {
+	if it.index >= 0 {
+		heap.Remove(q, it.index)
+	}
+}
+
+// peek returns the item that expires first, or nil if the queue is empty.
+func (q expiryQueue[K, V]) peek() *item[K, V] {
+	if len(q) == 0 {
+		return nil
+	}
+	return q[0]
+}
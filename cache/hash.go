@@ -0,0 +1,71 @@
+//go:build !go1.24
+
+package cache
+
+import (
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+var seed = maphash.MakeSeed()
+
+// hashKey hashes k. Equal keys always hash to the same value.
+//
+// Before Go 1.24 there is no way to hash an arbitrary comparable value, so
+// keys that are not strings, integers or floats are hashed by their
+// appendKey encoding. Keys that are interfaces holding values that are not
+// comparable panic, just like they would as map keys.
+func hashKey[K comparable](k K) uint64 {
+	switch k := any(k).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return mix(uint64(k))
+	case int8:
+		return mix(uint64(k))
+	case int16:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint8:
+		return mix(uint64(k))
+	case uint16:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case uintptr:
+		return mix(uint64(k))
+	case float32:
+		return hashFloat(float64(k))
+	case float64:
+		return hashFloat(k)
+	default:
+		var buf [64]byte
+		return maphash.Bytes(seed, appendKey(buf[:0], reflect.ValueOf(k)))
+	}
+}
+
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		// +0 and -0 are equal keys.
+		f = 0
+	}
+	return mix(math.Float64bits(f))
+}
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
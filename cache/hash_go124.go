@@ -0,0 +1,12 @@
+//go:build go1.24
+
+package cache
+
+import "hash/maphash"
+
+var seed = maphash.MakeSeed()
+
+// hashKey hashes k. Equal keys always hash to the same value.
+func hashKey[K comparable](k K) uint64 {
+	return maphash.Comparable(seed, k)
+}
@@ -0,0 +1,61 @@
+//go:build go1.23
+
+package cache
+
+import "iter"
+
+// All returns an iterator over the keys and values present in the cache.
+// See Range for its semantics.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.Range(yield)
+	}
+}
+
+// AllKeys returns an iterator over the keys present in the cache.
+// See Range for its semantics.
+func (c *Cache[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// AllValues returns an iterator over the values present in the cache.
+// See Range for its semantics.
+func (c *Cache[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		c.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// All returns an iterator over the keys and values present in the cache.
+// See Range for its semantics.
+func (s *Sharded[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.Range(yield)
+	}
+}
+
+// AllKeys returns an iterator over the keys present in the cache.
+// See Range for its semantics.
+func (s *Sharded[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		s.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// AllValues returns an iterator over the values present in the cache.
+// See Range for its semantics.
+func (s *Sharded[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		s.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
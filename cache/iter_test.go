@@ -0,0 +1,68 @@
+//go:build go1.23
+
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheAll(t *testing.T) {
+	c := New[string, int](time.Hour)
+	defer c.Close()
+	c.Set("foo", 1)
+	c.Set("bar", 2)
+	setExpired(c, "baz", 3)
+
+	got := make(map[string]int)
+	for k, v := range c.All() {
+		got[k] = v
+	}
+
+	want := map[string]int{"foo": 1, "bar": 2}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("\nwant: %v\ngot: %v", want, got)
+	}
+
+	var n int
+	for range c.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("\nbreak did not stop the iteration")
+	}
+}
+
+func TestCacheAllKeysValues(t *testing.T) {
+	c := New[string, int](time.Hour)
+	defer c.Close()
+	c.Set("foo", 1)
+	c.Set("bar", 2)
+	setExpired(c, "baz", 3)
+
+	keys := slices.Sorted(c.AllKeys())
+	if fmt.Sprint(keys) != "[bar foo]" {
+		t.Errorf("\nwant: [bar foo]\ngot: %v", keys)
+	}
+
+	values := slices.Sorted(c.AllValues())
+	if fmt.Sprint(values) != "[1 2]" {
+		t.Errorf("\nwant: [1 2]\ngot: %v", values)
+	}
+
+	var n int
+	for range c.AllKeys() {
+		n++
+		break
+	}
+	for range c.AllValues() {
+		n++
+		break
+	}
+	if n != 2 {
+		t.Errorf("\nbreak did not stop the iteration")
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+// Range calls f sequentially for each key and value present in the cache.
+// If f returns false, Range stops the iteration.
+//
+// Range iterates over a copy of the cache taken when it is called, so f may
+// use the cache, but it will not see the changes made after Range was called.
+func (c *Cache[K, V]) Range(f func(k K, v V) bool) {
+	type entry struct {
+		k K
+		v V
+	}
+
+	c.locker.RLock()
+	now := c.now()
+	entries := make([]entry, 0, len(c.cache))
+	for k, it := range c.cache {
+		if !it.expired(now) {
+			entries = append(entries, entry{k, it.v})
+		}
+	}
+	c.locker.RUnlock()
+
+	for _, e := range entries {
+		if !f(e.k, e.v) {
+			return
+		}
+	}
+}
+
+// Keys returns the keys present in the cache, in no particular order.
+func (c *Cache[K, V]) Keys() []K {
+	c.locker.RLock()
+	defer c.locker.RUnlock()
+	now := c.now()
+	keys := make([]K, 0, len(c.cache))
+	for k, it := range c.cache {
+		if !it.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Values returns the values present in the cache, in no particular order.
+func (c *Cache[K, V]) Values() []V {
+	c.locker.RLock()
+	defer c.locker.RUnlock()
+	now := c.now()
+	values := make([]V, 0, len(c.cache))
+	for _, it := range c.cache {
+		if !it.expired(now) {
+			values = append(values, it.v)
+		}
+	}
+	return values
+}
+
+// Snapshot returns a copy of the entries present in the cache. The copy is
+// taken while the cache is locked, so it is consistent.
+func (c *Cache[K, V]) Snapshot() map[K]V {
+	c.locker.RLock()
+	defer c.locker.RUnlock()
+	now := c.now()
+	m := make(map[K]V, len(c.cache))
+	for k, it := range c.cache {
+		if !it.expired(now) {
+			m[k] = it.v
+		}
+	}
+	return m
+}
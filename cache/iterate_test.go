@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheIterate(t *testing.T) {
+	newCache := func() *Cache[string, int] {
+		c := New[string, int](time.Hour)
+		c.Set("foo", 1)
+		c.Set("bar", 2)
+		c.Set("baz", 3)
+		setExpired(c, "qux", 4)
+		return c
+	}
+
+	for _, tc := range []struct {
+		name string
+		f    func(*Cache[string, int]) error
+	}{
+		{
+			"range",
+			func(c *Cache[string, int]) error {
+				got := make(map[string]int)
+				c.Range(func(k string, v int) bool {
+					got[k] = v
+					// The cache must be unlocked.
+					c.Set(k+k, v)
+					return true
+				})
+
+				want := map[string]int{"foo": 1, "bar": 2, "baz": 3}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					return fmt.Errorf("want: %v\ngot: %v", want, got)
+				}
+
+				return nil
+			},
+		},
+		{
+			"range stops",
+			func(c *Cache[string, int]) error {
+				var calls int
+				c.Range(func(string, int) bool {
+					calls++
+					return false
+				})
+
+				if calls != 1 {
+					return fmt.Errorf("expected 1 call but got %d instead", calls)
+				}
+
+				return nil
+			},
+		},
+		{
+			"keys",
+			func(c *Cache[string, int]) error {
+				got := c.Keys()
+				slices.Sort(got)
+				if want := []string{"bar", "baz", "foo"}; !slices.Equal(got, want) {
+					return fmt.Errorf("want: %v\ngot: %v", want, got)
+				}
+				return nil
+			},
+		},
+		{
+			"values",
+			func(c *Cache[string, int]) error {
+				got := c.Values()
+				slices.Sort(got)
+				if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+					return fmt.Errorf("want: %v\ngot: %v", want, got)
+				}
+				return nil
+			},
+		},
+		{
+			"snapshot",
+			func(c *Cache[string, int]) error {
+				got := c.Snapshot()
+				c.Set("foo", 10)
+
+				want := map[string]int{"foo": 1, "bar": 2, "baz": 3}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					return fmt.Errorf("want: %v\ngot: %v", want, got)
+				}
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newCache()
+			defer c.Close()
+			if err := tc.f(c); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+)
+
+// appendKey appends a deterministic encoding of the comparable value v to dst.
+// Equal values always have the same encoding, and values of the same type
+// that are not equal have different encodings.
+//
+// Strings are length prefixed, +0 and -0 are encoded the same, all struct
+// fields (exported or not) are encoded, and interfaces are encoded with the
+// name of their dynamic type. String methods are never called.
+//
+// Pointers and channels are encoded by address, so their encoding is only
+// stable for the lifetime of the process.
+func appendKey(dst []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(dst, 1)
+		}
+		return append(dst, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(dst, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.LittleEndian.AppendUint64(dst, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(dst, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		dst = appendFloat(dst, real(c))
+		return appendFloat(dst, imag(c))
+	case reflect.String:
+		s := v.String()
+		dst = binary.AppendUvarint(dst, uint64(len(s)))
+		return append(dst, s...)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			dst = appendKey(dst, v.Index(i))
+		}
+		return dst
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			dst = appendKey(dst, v.Field(i))
+		}
+		return dst
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return binary.LittleEndian.AppendUint64(dst, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(dst, 0)
+		}
+		e := v.Elem()
+		dst = append(dst, 1)
+		dst = appendKey(dst, reflect.ValueOf(typeName(e.Type())))
+		return appendKey(dst, e)
+	default:
+		// Slices, maps and funcs are not comparable, so they can't be part
+		// of a key.
+		panic("cache: key of type " + v.Type().String() + " is not comparable")
+	}
+}
+
+func appendFloat(dst []byte, f float64) []byte {
+	if f == 0 {
+		// +0 and -0 are equal keys.
+		f = 0
+	}
+	return binary.LittleEndian.AppendUint64(dst, math.Float64bits(f))
+}
+
+func typeName(t reflect.Type) string {
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type keyencStringer struct{ id int }
+
+func (keyencStringer) String() string { return "same" }
+
+func TestAppendKey(t *testing.T) {
+	type inner struct {
+		a int
+		b string
+	}
+	type outer struct {
+		inner
+		f float64
+		i any
+		p *int
+		c complex128
+		r [2]bool
+	}
+
+	x, y := 1, 1
+
+	for _, tc := range []struct {
+		name  string
+		a, b  any
+		equal bool
+	}{
+		{"equal strings", "foo", "foo", true},
+		{"different strings", "foo", "bar", false},
+		{"string boundaries", [2]string{"ab", "c"}, [2]string{"a", "bc"}, false},
+		{"signed zeros", 0.0, math.Copysign(0, -1), true},
+		{"unexported fields", inner{1, "foo"}, inner{1, "foo"}, true},
+		{"different unexported fields", inner{1, "foo"}, inner{2, "foo"}, false},
+		{"string methods are ignored", keyencStringer{1}, keyencStringer{2}, false},
+		{"same pointer", &x, &x, true},
+		{"different pointers", &x, &y, false},
+		{
+			"nested",
+			outer{inner{1, "foo"}, 1.5, "bar", &x, 1 + 2i, [2]bool{true, false}},
+			outer{inner{1, "foo"}, 1.5, "bar", &x, 1 + 2i, [2]bool{true, false}},
+			true,
+		},
+		{"interface types", outer{i: int(1)}, outer{i: int64(1)}, false},
+		{"nil interface", outer{}, outer{i: 0}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := appendKey(nil, reflect.ValueOf(tc.a))
+			b := appendKey(nil, reflect.ValueOf(tc.b))
+			if bytes.Equal(a, b) != tc.equal {
+				t.Errorf(
+					"\ntest '%s' failed\nexpected equal encodings to be %t\na: %x\nb: %x",
+					tc.name, tc.equal, a, b,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+// list is an intrusive doubly linked list of keys.
+// The zero value is not ready to use, call init first.
+type list[K comparable] struct {
+	root node[K]
+	len  int
+}
+
+type node[K comparable] struct {
+	k          K
+	prev, next *node[K]
+}
+
+func (l *list[K]) init() *list[K] {
+	l.root.prev = &l.root
+	l.root.next = &l.root
+	l.len = 0
+	return l
+}
+
+// pushFront inserts a new node with k at the front of the list.
+func (l *list[K]) pushFront(k K) *node[K] {
+	n := &node[K]{k: k}
+	l.insertAfter(n, &l.root)
+	return n
+}
+
+// moveToFront moves n to the front of the list.
+func (l *list[K]) moveToFront(n *node[K]) {
+	if l.root.next == n {
+		return
+	}
+	l.remove(n)
+	l.insertAfter(n, &l.root)
+}
+
+// back returns the last node of the list or nil if the list is empty.
+func (l *list[K]) back() *node[K] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// remove removes n from the list.
+func (l *list[K]) remove(n *node[K]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev = nil
+	n.next = nil
+	l.len--
+}
+
+func (l *list[K]) insertAfter(n, at *node[K]) {
+	n.prev = at
+	n.next = at.next
+	at.next.prev = n
+	at.next = n
+	l.len++
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLoaderPanicked is returned by GetOrLoad to the callers that were waiting
+// on a load function that panicked.
+var ErrLoaderPanicked = errors.New("cache: load function panicked")
+
+// call is an in flight or completed load of a key.
+type call[V any] struct {
+	wg  sync.WaitGroup
+	v   V
+	err error
+	// waiters is the number of callers waiting on the load, besides the one
+	// that started it. It is guarded by loadMu.
+	waiters int
+}
+
+type loadErr struct {
+	err error
+	exp int64
+}
+
+// GetOrLoad returns the value of k in the cache. If k is not found, load is
+// called to compute it and, if it does not return an error, the value will be
+// set to the cache.
+//
+// Concurrent calls of GetOrLoad for a missing key share a single call of load,
+// all of them receiving its result.
+//
+// Errors returned by load are not cached, unless WithErrorTTL is used.
+func (c *Cache[K, V]) GetOrLoad(k K, load func(K) (V, error)) (V, error) {
+	if v, ok := c.Get(k); ok {
+		return v, nil
+	}
+
+	c.loadMu.Lock()
+	if e, ok := c.loadErrs[k]; ok {
+		if c.now() < e.exp {
+			c.loadMu.Unlock()
+			var zero V
+			return zero, e.err
+		}
+		delete(c.loadErrs, k)
+	}
+
+	if cl, ok := c.calls[k]; ok {
+		cl.waiters++
+		c.loadMu.Unlock()
+		cl.wg.Wait()
+		return cl.v, cl.err
+	}
+
+	cl := c.newCall(k)
+	c.loadMu.Unlock()
+
+	c.load(k, cl, load, c.cacheFor)
+	return cl.v, cl.err
+}
+
+// refreshIfStale starts loading a new value for it in the background if it is
+// older than the duration set with WithRefreshAhead. Only one refresh of a key
+// runs at a time, and keys with a cached load error are not refreshed.
+//
+// refreshIfStale must be called with c.locker held.
+func (c *Cache[K, V]) refreshIfStale(it *item[K, V], now int64) {
+	if c.refreshLoad == nil || now-it.born <= int64(c.refreshAfter) {
+		return
+	}
+
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	if e, ok := c.loadErrs[it.k]; ok && now < e.exp {
+		return
+	}
+	if _, ok := c.calls[it.k]; ok {
+		return
+	}
+	go c.refresh(it.k, c.newCall(it.k), it.ttl)
+}
+
+// refresh loads k with the function set with WithRefreshAhead. It runs in its
+// own goroutine, so a panic of the function is recovered instead of crashing
+// the program, and handled by load as a failed load.
+func (c *Cache[K, V]) refresh(k K, cl *call[V], ttl time.Duration) {
+	defer func() {
+		recover()
+	}()
+	c.load(k, cl, c.refreshLoad, ttl)
+}
+
+// newCall registers a new load of k.
+//
+// newCall must be called with c.loadMu held.
+func (c *Cache[K, V]) newCall(k K) *call[V] {
+	if c.calls == nil {
+		c.calls = make(map[K]*call[V])
+	}
+	cl := new(call[V])
+	cl.wg.Add(1)
+	c.calls[k] = cl
+	return cl
+}
+
+// load calls f, sets its result to the cache for ttl, stores it in cl and
+// wakes up the callers waiting on it.
+func (c *Cache[K, V]) load(k K, cl *call[V], f func(K) (V, error), ttl time.Duration) {
+	normalReturn := false
+	defer func() {
+		if !normalReturn {
+			var zero V
+			cl.v, cl.err = zero, ErrLoaderPanicked
+		}
+
+		c.loadMu.Lock()
+		c.finishCall(k, cl)
+		c.loadMu.Unlock()
+		cl.wg.Done()
+	}()
+
+	cl.v, cl.err = f(k)
+	if cl.err == nil {
+		c.SetWithTTL(k, cl.v, ttl)
+	}
+	normalReturn = true
+}
+
+// finishCall unregisters the load of k, caching its error if WithErrorTTL is
+// used. The callers waiting on cl must be woken up after it returns.
+//
+// finishCall must be called with c.loadMu held.
+func (c *Cache[K, V]) finishCall(k K, cl *call[V]) {
+	delete(c.calls, k)
+	if cl.err != nil && c.errTTL > 0 {
+		if c.loadErrs == nil {
+			c.loadErrs = make(map[K]loadErr)
+		}
+		c.loadErrs[k] = loadErr{cl.err, c.now() + int64(c.errTTL)}
+	}
+}
+
+// forgetLoadErr deletes the cached load error of k, if any.
+func (c *Cache[K, V]) forgetLoadErr(k K) {
+	c.loadMu.Lock()
+	delete(c.loadErrs, k)
+	c.loadMu.Unlock()
+}
+
+// forgetLoadErrs deletes all cached load errors.
+func (c *Cache[K, V]) forgetLoadErrs() {
+	c.loadMu.Lock()
+	c.loadErrs = nil
+	c.loadMu.Unlock()
+}
+
+// pruneLoadErrs deletes all expired load errors.
+func (c *Cache[K, V]) pruneLoadErrs() {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	now := c.now()
+	for k, e := range c.loadErrs {
+		if now >= e.exp {
+			delete(c.loadErrs, k)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	errLoad := errors.New("load failed")
+
+	for _, tc := range []struct {
+		name string
+		opts []Option[string, int]
+		f    func(*Cache[string, int]) error
+	}{
+		{
+			"loads once",
+			nil,
+			func(c *Cache[string, int]) error {
+				var calls atomic.Int32
+				load := func(k string) (int, error) {
+					calls.Add(1)
+					time.Sleep(10 * time.Millisecond)
+					return len(k), nil
+				}
+
+				wg := new(sync.WaitGroup)
+				errs := make(chan error, 100)
+				for i := 0; i < 100; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						v, err := c.GetOrLoad("foo", load)
+						if err != nil || v != 3 {
+							errs <- fmt.Errorf("expected 3 and no error but got %d and %v instead", v, err)
+						}
+					}()
+				}
+				wg.Wait()
+				close(errs)
+				if err := <-errs; err != nil {
+					return err
+				}
+
+				if v, err := c.GetOrLoad("foo", load); err != nil || v != 3 {
+					return fmt.Errorf("expected 3 and no error but got %d and %v instead", v, err)
+				}
+
+				if calls.Load() != 1 {
+					return fmt.Errorf("expected load to be called once but it was called %d times", calls.Load())
+				}
+
+				return nil
+			},
+		},
+		{
+			"uses cached value",
+			nil,
+			func(c *Cache[string, int]) error {
+				c.Set("foo", 1)
+				v, err := c.GetOrLoad("foo", func(string) (int, error) {
+					return 0, errLoad
+				})
+				if err != nil || v != 1 {
+					return fmt.Errorf("expected 1 and no error but got %d and %v instead", v, err)
+				}
+				return nil
+			},
+		},
+		{
+			"errors are not cached",
+			nil,
+			func(c *Cache[string, int]) error {
+				var calls int
+				load := func(string) (int, error) {
+					calls++
+					return 0, errLoad
+				}
+
+				for i := 0; i < 2; i++ {
+					if _, err := c.GetOrLoad("foo", load); !errors.Is(err, errLoad) {
+						return fmt.Errorf("expected errLoad but got %v instead", err)
+					}
+				}
+
+				if calls != 2 {
+					return fmt.Errorf("expected load to be called 2 times but it was called %d times", calls)
+				}
+
+				if c.Contains("foo") {
+					return fmt.Errorf("key foo found in cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"errors are cached with error ttl",
+			[]Option[string, int]{WithErrorTTL[string, int](time.Hour)},
+			func(c *Cache[string, int]) error {
+				var calls int
+				load := func(string) (int, error) {
+					calls++
+					return 0, errLoad
+				}
+
+				for i := 0; i < 2; i++ {
+					if _, err := c.GetOrLoad("foo", load); !errors.Is(err, errLoad) {
+						return fmt.Errorf("expected errLoad but got %v instead", err)
+					}
+				}
+
+				if calls != 1 {
+					return fmt.Errorf("expected load to be called once but it was called %d times", calls)
+				}
+
+				c.Delete("foo")
+				c.GetOrLoad("foo", load)
+				if calls != 2 {
+					return fmt.Errorf("delete did not forget the cached error")
+				}
+
+				return nil
+			},
+		},
+		{
+			"panic",
+			nil,
+			func(c *Cache[string, int]) error {
+				started := make(chan struct{})
+				release := make(chan struct{})
+				waiterErr := make(chan error)
+
+				go func() {
+					defer func() { recover() }()
+					c.GetOrLoad("foo", func(string) (int, error) {
+						close(started)
+						<-release
+						panic("boom")
+					})
+				}()
+
+				<-started
+				go func() {
+					_, err := c.GetOrLoad("foo", func(string) (int, error) {
+						return 1, nil
+					})
+					waiterErr <- err
+				}()
+				waiting := waitFor(func() bool {
+					c.loadMu.Lock()
+					defer c.loadMu.Unlock()
+					return c.calls["foo"].waiters == 1
+				})
+				close(release)
+				if !waiting {
+					return fmt.Errorf("second GetOrLoad did not wait on the first load")
+				}
+
+				if err := <-waiterErr; !errors.Is(err, ErrLoaderPanicked) {
+					return fmt.Errorf("expected ErrLoaderPanicked but got %v instead", err)
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(0, tc.opts...)
+			defer c.Close()
+			if err := tc.f(c); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cacheFor time.Duration
+		f        func(c *Cache[string, int], clk *clock.Fake, loaded chan int, release chan struct{}) error
+	}{
+		{
+			"serves stale value while refreshing",
+			time.Hour,
+			func(c *Cache[string, int], clk *clock.Fake, loaded chan int, release chan struct{}) error {
+				c.Set("foo", 1)
+				clk.Advance(30 * time.Millisecond)
+
+				for i := 0; i < 10; i++ {
+					if v, ok := c.Get("foo"); !ok || v != 1 {
+						return fmt.Errorf("expected stale value 1 but got %d, %t instead", v, ok)
+					}
+				}
+
+				close(release)
+				if n := <-loaded; n != 1 {
+					return fmt.Errorf("expected load to be called once but it was called %d times", n)
+				}
+
+				if !waitFor(func() bool { v, _ := c.Get("foo"); return v == 2 }) {
+					return fmt.Errorf("value of foo was not refreshed")
+				}
+
+				return nil
+			},
+		},
+		{
+			"fresh values are not refreshed",
+			time.Hour,
+			func(c *Cache[string, int], clk *clock.Fake, loaded chan int, release chan struct{}) error {
+				defer close(release)
+				c.Set("foo", 1)
+				c.Get("foo")
+
+				c.loadMu.Lock()
+				_, refreshing := c.calls["foo"]
+				c.loadMu.Unlock()
+				if refreshing {
+					return fmt.Errorf("fresh value was refreshed")
+				}
+
+				return nil
+			},
+		},
+		{
+			"stale value is not served after ttl",
+			50 * time.Millisecond,
+			func(c *Cache[string, int], clk *clock.Fake, loaded chan int, release chan struct{}) error {
+				defer close(release)
+				c.Set("foo", 1)
+				clk.Advance(30 * time.Millisecond)
+				if _, ok := c.Get("foo"); !ok {
+					return fmt.Errorf("key foo not found in cache")
+				}
+
+				clk.Advance(30 * time.Millisecond)
+				if _, ok := c.Get("foo"); ok {
+					return fmt.Errorf("key foo found in cache")
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			loaded := make(chan int, 10)
+			release := make(chan struct{})
+			var calls atomic.Int32
+			clk := clock.NewFake(time.Now())
+			c := New(
+				tc.cacheFor,
+				WithRefreshAhead(20*time.Millisecond, func(string) (int, error) {
+					n := calls.Add(1)
+					<-release
+					loaded <- int(n)
+					return 2, nil
+				}),
+				WithClock[string, int](clk),
+			)
+			defer c.Close()
+
+			if err := tc.f(c, clk, loaded, release); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestRefreshAheadPanic(t *testing.T) {
+	called := make(chan struct{}, 1)
+	clk := clock.NewFake(time.Now())
+	c := New(
+		time.Hour,
+		WithRefreshAhead(20*time.Millisecond, func(string) (int, error) {
+			called <- struct{}{}
+			panic("refresh failed")
+		}),
+		WithErrorTTL[string, int](time.Hour),
+		WithClock[string, int](clk),
+	)
+	defer c.Close()
+
+	c.Set("foo", 1)
+	clk.Advance(30 * time.Millisecond)
+	if v, ok := c.Get("foo"); !ok || v != 1 {
+		t.Fatalf("\nexpected stale value 1 but got %d, %t instead", v, ok)
+	}
+	<-called
+
+	loadErr := func() error {
+		c.loadMu.Lock()
+		defer c.loadMu.Unlock()
+		return c.loadErrs["foo"].err
+	}
+	if !waitFor(func() bool { return loadErr() != nil }) {
+		t.Fatalf("\npanic of the refresh was not cached")
+	}
+	if err := loadErr(); !errors.Is(err, ErrLoaderPanicked) {
+		t.Errorf("\nexpected ErrLoaderPanicked but got %v instead", err)
+	}
+
+	if v, ok := c.Get("foo"); !ok || v != 1 {
+		t.Errorf("\nexpected stale value 1 but got %d, %t instead", v, ok)
+	}
+}
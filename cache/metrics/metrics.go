@@ -0,0 +1,158 @@
+// Package metrics exports the statistics of caches in the Prometheus text
+// exposition format, without depending on the Prometheus client libraries.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/phenpessoa/gutils/cache"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Source is anything that reports cache statistics, such as a *cache.Cache.
+type Source interface {
+	Stats() cache.Stats
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]Source)}
+}
+
+// Registry holds the caches whose metrics are exported, each one under its
+// own name, which is used as the value of the name label of its metrics.
+//
+// A Registry is an http.Handler serving the metrics of its caches.
+type Registry struct {
+	mu      sync.RWMutex
+	sources map[string]Source
+}
+
+// Register exports the metrics of s under name. It returns an error if name is
+// already registered.
+func (r *Registry) Register(name string, s Source) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sources[name]; ok {
+		return fmt.Errorf("metrics: cache %q already registered", name)
+	}
+	r.sources[name] = s
+	return nil
+}
+
+// Unregister stops exporting the metrics of the cache registered under name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sources, name)
+}
+
+// ServeHTTP writes the metrics of the registered caches to w.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteTo(w)
+}
+
+type metric struct {
+	name  string
+	help  string
+	typ   string
+	value func(cache.Stats) any
+}
+
+var statMetrics = [...]metric{
+	{
+		"cache_size", "Number of entries in the cache.", "gauge",
+		func(s cache.Stats) any { return s.Size },
+	},
+	{
+		"cache_weight", "Total weight of the entries in the cache.", "gauge",
+		func(s cache.Stats) any { return s.Weight },
+	},
+	{
+		"cache_hits_total", "Number of reads that found their key.", "counter",
+		func(s cache.Stats) any { return s.Hits },
+	},
+	{
+		"cache_misses_total", "Number of reads that did not find their key.", "counter",
+		func(s cache.Stats) any { return s.Misses },
+	},
+	{
+		"cache_sets_total", "Number of entries set.", "counter",
+		func(s cache.Stats) any { return s.Sets },
+	},
+	{
+		"cache_deletes_total", "Number of entries deleted.", "counter",
+		func(s cache.Stats) any { return s.Deletes },
+	},
+	{
+		"cache_expirations_total", "Number of expired entries deleted.", "counter",
+		func(s cache.Stats) any { return s.Expirations },
+	},
+	{
+		"cache_evictions_total", "Number of entries evicted because the cache was full.", "counter",
+		func(s cache.Stats) any { return s.Evictions },
+	},
+}
+
+// WriteTo writes the metrics of the registered caches to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.sources))
+	stats := make(map[string]cache.Stats, len(r.sources))
+	for name, s := range r.sources {
+		names = append(names, name)
+		stats[name] = s.Stats()
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range statMetrics {
+		writeHeader(bw, m.name, m.help, m.typ)
+		for _, name := range names {
+			fmt.Fprintf(bw, "%s{name=\"%s\"} %v\n", m.name, escape(name), m.value(stats[name]))
+		}
+	}
+
+	writeHeader(bw, "cache_gc_duration_seconds", "Time spent by the GC runs.", "summary")
+	for _, name := range names {
+		s := stats[name]
+		fmt.Fprintf(bw, "cache_gc_duration_seconds_sum{name=\"%s\"} %g\n", escape(name), s.GCDuration.Seconds())
+		fmt.Fprintf(bw, "cache_gc_duration_seconds_count{name=\"%s\"} %d\n", escape(name), s.GCRuns)
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes s to be used as a label value.
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
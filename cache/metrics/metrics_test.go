@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache"
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+var (
+	_ Source = (*cache.Cache[string, int])(nil)
+	_ Source = (*cache.Sharded[string, int])(nil)
+)
+
+func TestRegistry(t *testing.T) {
+	foo := cache.New[string, int](time.Hour, cache.WithClock[string, int](clock.NewFake(time.Now())))
+	defer foo.Close()
+	foo.Set("a", 1)
+	foo.Set("b", 2)
+	foo.Get("a")
+	foo.Get("c")
+	foo.Delete("b")
+	foo.TickGC()
+
+	bar := cache.New[int, string](0)
+	defer bar.Close()
+
+	r := NewRegistry()
+	if err := r.Register("foo", foo); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(`b"a\r`, bar); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("foo", bar); err == nil {
+		t.Errorf("registered foo twice")
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("expected content type %q but got %q instead", ContentType, ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE cache_size gauge\n" +
+			`cache_size{name="b\"a\\r"} 0` + "\n" +
+			`cache_size{name="foo"} 1` + "\n",
+		"# TYPE cache_hits_total counter\n",
+		`cache_hits_total{name="foo"} 1` + "\n",
+		`cache_misses_total{name="foo"} 1` + "\n",
+		`cache_sets_total{name="foo"} 2` + "\n",
+		`cache_deletes_total{name="foo"} 1` + "\n",
+		`cache_evictions_total{name="foo"} 0` + "\n",
+		"# TYPE cache_gc_duration_seconds summary\n",
+		`cache_gc_duration_seconds_sum{name="foo"} 0` + "\n",
+		`cache_gc_duration_seconds_count{name="foo"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("\nmissing:\n%s\ngot:\n%s", want, body)
+		}
+	}
+
+	r.Unregister("foo")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(rec.Body.String(), `name="foo"`) {
+		t.Errorf("unregistered cache was exported")
+	}
+}
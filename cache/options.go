@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// Option configures a Cache created by New.
+type Option[K comparable, V any] func(*Cache[K, V])
+
+// WithCapacity limits the cache to n entries. When a new entry is set in a
+// full cache, an entry is evicted according to the cache Policy, which is LRU
+// unless WithPolicy is used.
+//
+// A capacity of 0 or less means the cache is not bounded.
+func WithCapacity[K comparable, V any](n int) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.capacity = n
+	}
+}
+
+// Weigher returns the weight of an entry, such as its size in bytes. Negative
+// weights are treated as 0.
+type Weigher[K comparable, V any] func(k K, v V) int64
+
+// WithMaxWeight limits the total weight of the entries of the cache to limit.
+// When the cache gets heavier than limit, entries are evicted according to the
+// cache Policy, which is LRU unless WithPolicy is used. Setting an entry
+// heavier than limit evicts its previous value instead of storing it.
+//
+// The weight of an entry is computed by w, or passed to SetWithWeight. If w
+// is nil, every entry set without SetWithWeight weighs 1.
+//
+// WithMaxWeight can be combined with WithCapacity, in which case both limits
+// are enforced.
+func WithMaxWeight[K comparable, V any](limit int64, w Weigher[K, V]) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.maxWeight = limit
+		c.weigher = w
+	}
+}
+
+// WithPolicy sets the Policy used to pick which entry to evict when the cache
+// is full. p must not be shared with other caches.
+//
+// WithPolicy has no effect unless WithCapacity or WithMaxWeight is also used.
+func WithPolicy[K comparable, V any](p Policy[K]) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.policy = p
+	}
+}
+
+// WithPolicyFunc is like WithPolicy, but the Policy is created by calling f,
+// once for every cache the option is applied to. It is meant for NewSharded,
+// whose shards can not share a Policy.
+func WithPolicyFunc[K comparable, V any](f func() Policy[K]) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.policy = f()
+	}
+}
+
+// WithOnEvict sets f to be called whenever an entry is removed from the cache,
+// with the reason it was removed. Overwriting an entry with a different value
+// calls f with its old value and ReasonReplaced. Overwriting it with an
+// identical comparable value does not call f.
+//
+// f is called after the cache is unlocked, so it may use the cache.
+func WithOnEvict[K comparable, V any](f func(k K, v V, reason EvictReason)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.onEvict = f
+	}
+}
+
+// WithOnExpire sets f to be called whenever an expired entry is removed from
+// the cache, be it by the GC or by reading it. f is called before the function
+// set with WithOnEvict, if any.
+//
+// f is called after the cache is unlocked, so it may use the cache.
+func WithOnExpire[K comparable, V any](f func(k K, v V)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.onExpire = f
+	}
+}
+
+// WithErrorTTL makes GetOrLoad cache the errors returned by its load function
+// for d, so that a failing backend is not called on every miss.
+func WithErrorTTL[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.errTTL = d
+	}
+}
+
+// WithSlidingExpiration makes the entries of the cache expire when they were
+// not read for their ttl, instead of when they were not set for it. Every time
+// Get, GetSet or Contains finds an entry, its ttl starts over.
+//
+// If maxAge is greater than 0, entries will expire maxAge after they were set
+// no matter how often they are read, even those cached forever.
+func WithSlidingExpiration[K comparable, V any](maxAge time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.sliding = true
+		c.maxAge = maxAge
+	}
+}
+
+// WithRefreshAhead makes the cache refresh entries that are older than after,
+// instead of waiting for them to expire.
+//
+// When an entry older than after is read, its current value is returned and
+// load is called in the background to replace it, keeping the entry ttl. Only
+// one refresh of a key runs at a time, shared with GetOrLoad. Once an entry
+// expires, its stale value is no longer returned, so after should be shorter
+// than the ttl of the entries.
+//
+// Errors returned by load are only cached if WithErrorTTL is used, in which
+// case the entry will not be refreshed again until the error expires. If load
+// panics, the panic is recovered and ErrLoaderPanicked is cached instead.
+func WithRefreshAhead[K comparable, V any](after time.Duration, load func(K) (V, error)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.refreshAfter = after
+		c.refreshLoad = load
+	}
+}
+
+// WithInvalidationBus subscribes the cache to bus, so that calling Delete or
+// Wipe on any cache subscribed to it with the same name deletes the entries
+// from all of them. The keys are encoded with codec to be sent over the bus.
+//
+// Invalidations received from the bus delete the entries with ReasonDeleted or
+// ReasonWiped, as Delete and Wipe do, but are not published again.
+func WithInvalidationBus[K comparable, V any](bus Bus, name string, codec Codec) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.bus = bus
+		c.busName = name
+		c.busCodec = codec
+	}
+}
+
+// WithClock makes the cache tell the time with clk instead of the system
+// clock, which is mostly useful to test expiry with a fake clock. The GC of the
+// cache also ticks with clk.
+func WithClock[K comparable, V any](clk clock.Clock) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.clock = clk
+	}
+}
+
+// WithGCInterval makes the GC of the cache tick every d, regardless of the
+// lifetime of its entries. A d of 0 or less disables the GC, in which case
+// expired entries are only deleted when they are read or when TickGC is called.
+func WithGCInterval[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.gcFixed = true
+		c.gcEvery = d
+	}
+}
+
+// WithPrefixIndex makes the cache keep its keys in a radix tree, so that
+// DeletePrefix only looks at the keys starting with the prefix instead of at
+// every key. Keeping the tree makes setting new keys and deleting keys slower.
+func WithPrefixIndex[V any]() Option[string, V] {
+	return func(c *Cache[string, V]) {
+		c.keys = new(radixTree)
+	}
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Codec creates the encoders and decoders used to save and load caches.
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Encoder writes values to a stream.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads values written by an Encoder from a stream. Decode must return
+// io.EOF when the stream has no more values.
+type Decoder interface {
+	Decode(v any) error
+}
+
+var (
+	// GobCodec is a Codec using encoding/gob. Concrete types stored in
+	// interface keys or values must be registered with gob.Register.
+	GobCodec Codec = gobCodec{}
+
+	// JSONCodec is a Codec using encoding/json.
+	JSONCodec Codec = jsonCodec{}
+)
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (gobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
+func (jsonCodec) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+// record is how an entry is saved.
+type record[K comparable, V any] struct {
+	Key   K
+	Value V
+	// Expires is when the entry expires in Unix nanoseconds, or 0 if it never
+	// does.
+	Expires int64
+}
+
+// expired reports whether the record expired at now.
+func (r *record[K, V]) expired(now int64) bool {
+	return r.Expires != 0 && now > r.Expires
+}
+
+// Save writes the entries present in the cache to w, using codec. The entries
+// can be restored with Load.
+//
+// The entries are copied while the cache is locked, but encoded after it is
+// unlocked.
+func (c *Cache[K, V]) Save(w io.Writer, codec Codec) error {
+	return saveRecords(w, codec, c.records(nil))
+}
+
+// records appends the entries present in the cache to dst, as they are saved.
+func (c *Cache[K, V]) records(dst []record[K, V]) []record[K, V] {
+	c.locker.RLock()
+	defer c.locker.RUnlock()
+	now := c.now()
+	dst = slices.Grow(dst, len(c.cache))
+	for k, it := range c.cache {
+		if !it.expired(now) {
+			dst = append(dst, record[K, V]{k, it.v, it.exp})
+		}
+	}
+	return dst
+}
+
+func saveRecords[K comparable, V any](w io.Writer, codec Codec, records []record[K, V]) error {
+	enc := codec.NewEncoder(w)
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return fmt.Errorf("cache: failed to encode entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Load reads the entries written by Save from r, using codec, and sets them
+// to the cache with their remaining ttl. Entries that expired since they were
+// saved are skipped.
+//
+// If Load fails, the entries read before the failure are kept in the cache.
+func (c *Cache[K, V]) Load(r io.Reader, codec Codec) error {
+	return loadRecords(r, codec, c.now, c.SetWithTTL)
+}
+
+// loadRecords decodes the records written by saveRecords from r and sets those
+// that did not expire at now with set.
+func loadRecords[K comparable, V any](r io.Reader, codec Codec, now func() int64, set func(K, V, time.Duration)) error {
+	dec := codec.NewDecoder(r)
+	for {
+		var rec record[K, V]
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("cache: failed to decode entry: %w", err)
+		}
+
+		var ttl time.Duration
+		if rec.Expires != 0 {
+			ttl = time.Duration(rec.Expires - now())
+			if ttl <= 0 {
+				continue
+			}
+		}
+		set(rec.Key, rec.Value, ttl)
+	}
+}
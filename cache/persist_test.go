@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+type persisted struct {
+	Name string
+	Tags []string
+}
+
+func TestCachePersist(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		codec Codec
+	}{
+		{"gob", GobCodec},
+		{"json", JSONCodec},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := New(time.Hour, WithClock[string, persisted](clk))
+			defer c.Close()
+			c.Set("foo", persisted{"foo", []string{"a", "b"}})
+			c.SetWithTTL("bar", persisted{Name: "bar"}, 0)
+			c.SetWithTTL("baz", persisted{Name: "baz"}, 20*time.Millisecond)
+			setExpired(c, "qux", persisted{Name: "qux"})
+
+			buf := new(bytes.Buffer)
+			if err := c.Save(buf, tc.codec); err != nil {
+				t.Fatalf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+
+			clk.Advance(30 * time.Millisecond)
+
+			loaded := New(time.Hour, WithClock[string, persisted](clk))
+			defer loaded.Close()
+			if err := loaded.Load(buf, tc.codec); err != nil {
+				t.Fatalf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+
+			want := map[string]persisted{
+				"foo": {"foo", []string{"a", "b"}},
+				"bar": {Name: "bar"},
+			}
+			if got := loaded.Snapshot(); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("\ntest '%s' failed\nwant: %v\ngot: %v", tc.name, want, got)
+			}
+
+			loaded.locker.RLock()
+			foo, bar := *loaded.cache["foo"], *loaded.cache["bar"]
+			loaded.locker.RUnlock()
+
+			if foo.ttl <= 0 || foo.ttl > time.Hour {
+				t.Errorf("\ntest '%s' failed\nfoo ttl was not kept, it is %v", tc.name, foo.ttl)
+			}
+
+			if bar.ttl != 0 {
+				t.Errorf("\ntest '%s' failed\nbar does not live forever", tc.name)
+			}
+		})
+	}
+}
+
+func TestCacheLoadError(t *testing.T) {
+	c := New[string, int](0)
+	defer c.Close()
+
+	err := c.Load(strings.NewReader(`{"Key":"foo","Value":1} {"Key":"bar","Value":"x"}`), JSONCodec)
+	if err == nil {
+		t.Fatal("\nexpected an error")
+	}
+
+	if !c.Contains("foo") {
+		t.Errorf("\nexpected foo to be loaded before the error")
+	}
+}
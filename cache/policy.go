@@ -0,0 +1,189 @@
+package cache
+
+// Policy decides which entry is evicted when a Cache is full.
+//
+// A Policy is owned by a single Cache and is only called while the cache is
+// locked, so implementations do not need to be safe for concurrent use.
+type Policy[K comparable] interface {
+	// Add records that k was set in the cache. k may already be tracked, in
+	// which case Add counts as a use of k.
+	Add(k K)
+
+	// Access records that k was read from the cache.
+	Access(k K)
+
+	// Remove forgets k, which was deleted from the cache.
+	Remove(k K)
+
+	// Evict forgets and returns the key that must be evicted from the cache.
+	// It returns false if the policy does not track any keys.
+	Evict() (K, bool)
+}
+
+// NewLRU returns a Policy that evicts the least recently used key.
+func NewLRU[K comparable]() Policy[K] {
+	return &lru[K]{nodes: make(map[K]*node[K]), ll: new(list[K]).init()}
+}
+
+type lru[K comparable] struct {
+	nodes map[K]*node[K]
+	ll    *list[K]
+}
+
+func (l *lru[K]) Add(k K) {
+	if n, ok := l.nodes[k]; ok {
+		l.ll.moveToFront(n)
+		return
+	}
+	l.nodes[k] = l.ll.pushFront(k)
+}
+
+func (l *lru[K]) Access(k K) {
+	if n, ok := l.nodes[k]; ok {
+		l.ll.moveToFront(n)
+	}
+}
+
+func (l *lru[K]) Remove(k K) {
+	if n, ok := l.nodes[k]; ok {
+		l.ll.remove(n)
+		delete(l.nodes, k)
+	}
+}
+
+func (l *lru[K]) Evict() (K, bool) {
+	n := l.ll.back()
+	if n == nil {
+		var zero K
+		return zero, false
+	}
+	l.ll.remove(n)
+	delete(l.nodes, n.k)
+	return n.k, true
+}
+
+// NewFIFO returns a Policy that evicts the oldest key, regardless of how it
+// was used. Overwriting a key does not change its age.
+func NewFIFO[K comparable]() Policy[K] {
+	return &fifo[K]{nodes: make(map[K]*node[K]), ll: new(list[K]).init()}
+}
+
+type fifo[K comparable] struct {
+	nodes map[K]*node[K]
+	ll    *list[K]
+}
+
+func (f *fifo[K]) Add(k K) {
+	if _, ok := f.nodes[k]; !ok {
+		f.nodes[k] = f.ll.pushFront(k)
+	}
+}
+
+func (f *fifo[K]) Access(K) {}
+
+func (f *fifo[K]) Remove(k K) {
+	if n, ok := f.nodes[k]; ok {
+		f.ll.remove(n)
+		delete(f.nodes, k)
+	}
+}
+
+func (f *fifo[K]) Evict() (K, bool) {
+	n := f.ll.back()
+	if n == nil {
+		var zero K
+		return zero, false
+	}
+	f.ll.remove(n)
+	delete(f.nodes, n.k)
+	return n.k, true
+}
+
+// NewLFU returns a Policy that evicts the least frequently used key. Ties are
+// broken by evicting the least recently used of the candidates.
+func NewLFU[K comparable]() Policy[K] {
+	l := &lfu[K]{entries: make(map[K]lfuEntry[K])}
+	l.root.prev = &l.root
+	l.root.next = &l.root
+	return l
+}
+
+// lfu is the O(1) LFU described in http://dhruvbird.com/lfu.pdf.
+// Keys are kept in buckets of the same frequency, and the buckets are kept in
+// a list sorted by frequency.
+type lfu[K comparable] struct {
+	entries map[K]lfuEntry[K]
+	root    lfuBucket[K]
+}
+
+type lfuEntry[K comparable] struct {
+	n *node[K]
+	b *lfuBucket[K]
+}
+
+type lfuBucket[K comparable] struct {
+	freq       uint64
+	keys       list[K]
+	prev, next *lfuBucket[K]
+}
+
+func (l *lfu[K]) Add(k K) {
+	if _, ok := l.entries[k]; ok {
+		l.Access(k)
+		return
+	}
+
+	b := l.root.next
+	if b == &l.root || b.freq != 1 {
+		b = l.insertBucket(1, &l.root)
+	}
+	l.entries[k] = lfuEntry[K]{b.keys.pushFront(k), b}
+}
+
+func (l *lfu[K]) Access(k K) {
+	e, ok := l.entries[k]
+	if !ok {
+		return
+	}
+
+	next := e.b.next
+	if next == &l.root || next.freq != e.b.freq+1 {
+		next = l.insertBucket(e.b.freq+1, e.b)
+	}
+	l.removeFromBucket(e)
+	l.entries[k] = lfuEntry[K]{next.keys.pushFront(k), next}
+}
+
+func (l *lfu[K]) Remove(k K) {
+	if e, ok := l.entries[k]; ok {
+		l.removeFromBucket(e)
+		delete(l.entries, k)
+	}
+}
+
+func (l *lfu[K]) Evict() (K, bool) {
+	b := l.root.next
+	if b == &l.root {
+		var zero K
+		return zero, false
+	}
+	k := b.keys.back().k
+	l.Remove(k)
+	return k, true
+}
+
+func (l *lfu[K]) insertBucket(freq uint64, at *lfuBucket[K]) *lfuBucket[K] {
+	b := &lfuBucket[K]{freq: freq, prev: at, next: at.next}
+	b.keys.init()
+	at.next.prev = b
+	at.next = b
+	return b
+}
+
+func (l *lfu[K]) removeFromBucket(e lfuEntry[K]) {
+	e.b.keys.remove(e.n)
+	if e.b.keys.len == 0 {
+		e.b.prev.next = e.b.next
+		e.b.next.prev = e.b.prev
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy Policy[string]
+		f      func(Policy[string])
+		want   []string
+	}{
+		{
+			"lru",
+			NewLRU[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Add("baz")
+				p.Access("foo")
+			},
+			[]string{"bar", "baz", "foo"},
+		},
+		{
+			"lru add counts as use",
+			NewLRU[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Add("foo")
+			},
+			[]string{"bar", "foo"},
+		},
+		{
+			"lru remove",
+			NewLRU[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Remove("foo")
+			},
+			[]string{"bar"},
+		},
+		{
+			"fifo",
+			NewFIFO[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Add("baz")
+				p.Access("foo")
+				p.Add("foo")
+			},
+			[]string{"foo", "bar", "baz"},
+		},
+		{
+			"fifo remove",
+			NewFIFO[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Remove("foo")
+			},
+			[]string{"bar"},
+		},
+		{
+			"lfu",
+			NewLFU[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Add("baz")
+				p.Access("foo")
+				p.Access("foo")
+				p.Access("baz")
+			},
+			[]string{"bar", "baz", "foo"},
+		},
+		{
+			"lfu ties evict least recently used",
+			NewLFU[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Access("bar")
+				p.Access("foo")
+			},
+			[]string{"bar", "foo"},
+		},
+		{
+			"lfu remove",
+			NewLFU[string](),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Access("foo")
+				p.Remove("foo")
+				p.Add("baz")
+			},
+			[]string{"bar", "baz"},
+		},
+		{
+			"tinylfu",
+			NewTinyLFU[string](2),
+			func(p Policy[string]) {
+				p.Add("foo")
+				p.Add("bar")
+				p.Access("foo")
+			},
+			[]string{"bar", "foo"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.f(tc.policy)
+
+			var got []string
+			for {
+				k, ok := tc.policy.Evict()
+				if !ok {
+					break
+				}
+				got = append(got, k)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("\ntest '%s' failed\nwant: %v\ngot: %v", tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTinyLFUScanResistance(t *testing.T) {
+	const capacity = 100
+	c := New[int, int](0,
+		WithCapacity[int, int](capacity),
+		WithPolicy[int, int](NewTinyLFU[int](capacity)),
+	)
+
+	for i := 0; i < capacity/2; i++ {
+		c.Set(i, i)
+		for j := 0; j < 10; j++ {
+			c.Get(i)
+		}
+	}
+
+	for i := capacity; i < capacity*10; i++ {
+		c.Set(i, i)
+	}
+
+	// The hot keys wait in probation with an estimate of about 5 once the
+	// sketch is halved, and a scanned key only replaces one if its counters
+	// collide with others in all 4 rows. The seed of hashKey is random, so
+	// this happens now and then: over 20000 runs, no hot key was evicted in
+	// 83% of them, and never more than 3. Allowing 5 keeps the test stable
+	// while still failing if the scan flushes the hot keys.
+	var evicted int
+	for i := 0; i < capacity/2; i++ {
+		if !c.Contains(i) {
+			evicted++
+		}
+	}
+	if evicted > capacity/20 {
+		t.Errorf("\n%d hot keys were evicted by a scan", evicted)
+	}
+
+	if c.Len() != capacity {
+		t.Errorf("\ncache len is not %d, it is %d", capacity, c.Len())
+	}
+}
+
+func Benchmark_Policy_Zipf(b *testing.B) {
+	const capacity = 1000
+	for _, bc := range []struct {
+		name   string
+		policy func() Policy[uint64]
+	}{
+		{"LRU", NewLRU[uint64]},
+		{"LFU", NewLFU[uint64]},
+		{"FIFO", NewFIFO[uint64]},
+		{"TinyLFU", func() Policy[uint64] { return NewTinyLFU[uint64](capacity) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			c := New[uint64, uint64](0,
+				WithCapacity[uint64, uint64](capacity),
+				WithPolicy[uint64, uint64](bc.policy()),
+			)
+			zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, 1<<20)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			var hits int
+			for i := 0; i < b.N; i++ {
+				k := zipf.Uint64()
+				if _, ok := c.Get(k); ok {
+					hits++
+					continue
+				}
+				c.Set(k, k)
+			}
+			b.ReportMetric(float64(hits)/float64(b.N)*100, "hit%")
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package cache
+
+import "strings"
+
+// keyIndex is an index of the keys of a cache, kept in sync with its map.
+type keyIndex[K comparable] interface {
+	insert(k K)
+	remove(k K)
+	reset()
+}
+
+// DeletePrefix deletes all entries of c whose key starts with prefix and
+// returns how many were deleted.
+//
+// DeletePrefix only looks at the matching keys if c was created with
+// WithPrefixIndex, otherwise it looks at every key in the cache.
+//
+// If the cache uses an invalidation Bus, the matching entries are also deleted
+// from the other caches on the bus.
+func DeletePrefix[V any](c *Cache[string, V], prefix string) int {
+	n := deletePrefix(c, prefix)
+	c.publishPrefix(prefix)
+	return n
+}
+
+func deletePrefix[V any](c *Cache[string, V], prefix string) int {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+
+	var keys []string
+	if t, ok := c.keys.(*radixTree); ok {
+		t.walkPrefix(prefix, func(k string) {
+			keys = append(keys, k)
+		})
+	} else {
+		for k := range c.cache {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	for _, k := range keys {
+		c.delete(k, ReasonDeleted)
+	}
+	return len(keys)
+}
+
+// radixTree is a keyIndex of string keys, to find the keys sharing a prefix
+// without looking at the others.
+type radixTree struct {
+	root radixNode
+}
+
+type radixNode struct {
+	// prefix is the label of the edge leading to the node.
+	prefix   string
+	children map[byte]*radixNode
+	// leaf means the path to the node is a key.
+	leaf bool
+}
+
+func (t *radixTree) insert(k string) {
+	n := &t.root
+	for k != "" {
+		child := n.children[k[0]]
+		if child == nil {
+			n.addChild(&radixNode{prefix: k, leaf: true})
+			return
+		}
+
+		l := commonPrefix(k, child.prefix)
+		if l < len(child.prefix) {
+			// Split the edge to child where k leaves it.
+			mid := &radixNode{prefix: child.prefix[:l]}
+			child.prefix = child.prefix[l:]
+			mid.addChild(child)
+			n.children[mid.prefix[0]] = mid
+			child = mid
+		}
+		k = k[l:]
+		n = child
+	}
+	n.leaf = true
+}
+
+func (t *radixTree) remove(k string) {
+	var parent *radixNode
+	n := &t.root
+	for k != "" {
+		child := n.children[k[0]]
+		if child == nil || !strings.HasPrefix(k, child.prefix) {
+			return
+		}
+		k = k[len(child.prefix):]
+		parent, n = n, child
+	}
+	if !n.leaf {
+		return
+	}
+
+	n.leaf = false
+	if parent == nil {
+		return
+	}
+
+	switch len(n.children) {
+	case 0:
+		delete(parent.children, n.prefix[0])
+		if parent != &t.root && !parent.leaf && len(parent.children) == 1 {
+			parent.mergeChild()
+		}
+	case 1:
+		n.mergeChild()
+	}
+}
+
+func (t *radixTree) reset() {
+	t.root = radixNode{}
+}
+
+// walkPrefix calls f with every key in t starting with prefix.
+func (t *radixTree) walkPrefix(prefix string, f func(k string)) {
+	n := &t.root
+	path := ""
+	for prefix != "" {
+		child := n.children[prefix[0]]
+		if child == nil {
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(prefix, child.prefix):
+			prefix = prefix[len(child.prefix):]
+		case strings.HasPrefix(child.prefix, prefix):
+			prefix = ""
+		default:
+			return
+		}
+		path += child.prefix
+		n = child
+	}
+	n.walk(path, f)
+}
+
+func (n *radixNode) walk(path string, f func(k string)) {
+	if n.leaf {
+		f(path)
+	}
+	for _, child := range n.children {
+		child.walk(path+child.prefix, f)
+	}
+}
+
+func (n *radixNode) addChild(child *radixNode) {
+	if n.children == nil {
+		n.children = make(map[byte]*radixNode)
+	}
+	n.children[child.prefix[0]] = child
+}
+
+// mergeChild merges the only child of n into n.
+func (n *radixNode) mergeChild() {
+	for _, child := range n.children {
+		n.prefix += child.prefix
+		n.children = child.children
+		n.leaf = child.leaf
+	}
+}
+
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDeletePrefix(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option[string, int]
+	}{
+		{"scan", nil},
+		{"index", []Option[string, int]{WithPrefixIndex[int]()}},
+	} {
+		c := New(0, tc.opts...)
+		for i, k := range []string{"user:1", "user:2", "user:10", "users", "use", "order:1", ""} {
+			c.Set(k, i)
+		}
+
+		if n := DeletePrefix(c, "user:"); n != 3 {
+			t.Errorf("\ntest '%s' failed\nerr: expected 3 entries to be deleted but got %d instead", tc.name, n)
+		}
+
+		keys := c.Keys()
+		sort.Strings(keys)
+		if want := []string{"", "order:1", "use", "users"}; !slices.Equal(keys, want) {
+			t.Errorf("\ntest '%s' failed\nwant: %q\ngot: %q", tc.name, want, keys)
+		}
+
+		if n := DeletePrefix(c, "zzz"); n != 0 {
+			t.Errorf("\ntest '%s' failed\nerr: expected no entry to be deleted but got %d instead", tc.name, n)
+		}
+
+		if n := DeletePrefix(c, ""); n != 4 || c.Len() != 0 {
+			t.Errorf("\ntest '%s' failed\nerr: expected all entries to be deleted but got %d instead", tc.name, n)
+		}
+
+		c.Close()
+	}
+}
+
+func TestRadixTree(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	key := func() string {
+		b := make([]byte, r.Intn(6))
+		for i := range b {
+			b[i] = "abc"[r.Intn(3)]
+		}
+		return string(b)
+	}
+
+	tree := new(radixTree)
+	keys := make(map[string]struct{})
+	for i := 0; i < 10000; i++ {
+		k := key()
+		if r.Intn(3) == 0 {
+			tree.remove(k)
+			delete(keys, k)
+		} else {
+			tree.insert(k)
+			keys[k] = struct{}{}
+		}
+
+		prefix := key()
+		var got, want []string
+		tree.walkPrefix(prefix, func(k string) {
+			got = append(got, k)
+		})
+		for k := range keys {
+			if strings.HasPrefix(k, prefix) {
+				want = append(want, k)
+			}
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("\nprefix: %q\nwant: %q\ngot: %q", prefix, want, got)
+		}
+	}
+}
+
+func Benchmark_DeletePrefix(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []Option[string, int]
+	}{
+		{"scan", nil},
+		{"index", []Option[string, int]{WithPrefixIndex[int]()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			c := New(0, bc.opts...)
+			defer c.Close()
+			for i := 0; i < 100000; i++ {
+				c.Set("user:"+strconv.Itoa(i), i)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Set("order:1", i)
+				DeletePrefix(c, "order:")
+			}
+		})
+	}
+}
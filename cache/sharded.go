@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// NewSharded creates a new Sharded cache made of n shards, each of them a
+// Cache created with New(cacheFor, opts...). n is rounded up to a power of 2.
+//
+// The options are applied to every shard, so the capacity set by WithCapacity
+// is the capacity of each shard. WithPolicy must not be used, since a Policy
+// can not be shared between caches. Use WithPolicyFunc instead, so that every
+// shard gets its own Policy:
+//
+//	cache.NewSharded(16, 0,
+//		cache.WithCapacity[string, int](1000),
+//		cache.WithPolicyFunc[string, int](cache.NewLFU[string]),
+//	)
+func NewSharded[K comparable, V any](n int, cacheFor time.Duration, opts ...Option[K, V]) *Sharded[K, V] {
+	return newSharded(n, func() *Cache[K, V] {
+		return New(cacheFor, opts...)
+	})
+}
+
+// NewShardedContext is like NewSharded, but the shards will be closed when ctx
+// is done. See Cache.Close.
+func NewShardedContext[K comparable, V any](ctx context.Context, n int, cacheFor time.Duration, opts ...Option[K, V]) *Sharded[K, V] {
+	return newSharded(n, func() *Cache[K, V] {
+		return NewContext(ctx, cacheFor, opts...)
+	})
+}
+
+func newSharded[K comparable, V any](n int, newShard func() *Cache[K, V]) *Sharded[K, V] {
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+
+	s := &Sharded[K, V]{shards: make([]*Cache[K, V], size), mask: uint64(size - 1)}
+	for i := range s.shards {
+		s.shards[i] = newShard()
+	}
+	return s
+}
+
+// Sharded is a thread safe cache that spreads its keys between many Caches,
+// each one with its own lock and GC, so that concurrent operations on
+// different keys rarely wait for each other.
+type Sharded[K comparable, V any] struct {
+	shards []*Cache[K, V]
+	mask   uint64
+}
+
+func (s *Sharded[K, V]) shard(k K) *Cache[K, V] {
+	return s.shards[hashKey(k)&s.mask]
+}
+
+// Set sets a new value to the cache. See Cache.Set.
+func (s *Sharded[K, V]) Set(k K, v V) {
+	s.shard(k).Set(k, v)
+}
+
+// SetWithTTL sets a new value to the cache that will be cached for ttl.
+// See Cache.SetWithTTL.
+func (s *Sharded[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	s.shard(k).SetWithTTL(k, v, ttl)
+}
+
+// SetWithWeight sets a new value to the cache with the passed weight.
+// See Cache.SetWithWeight.
+func (s *Sharded[K, V]) SetWithWeight(k K, v V, weight int64) {
+	s.shard(k).SetWithWeight(k, v, weight)
+}
+
+// SetWithTags sets a new value to the cache and tags it with tags.
+// See Cache.SetWithTags.
+func (s *Sharded[K, V]) SetWithTags(k K, v V, tags ...string) {
+	s.shard(k).SetWithTags(k, v, tags...)
+}
+
+// Get returns the value in the cache of the passed key and if it was found or
+// not. See Cache.Get.
+func (s *Sharded[K, V]) Get(k K) (V, bool) {
+	return s.shard(k).Get(k)
+}
+
+// Contains reports whether k is present in the cache.
+func (s *Sharded[K, V]) Contains(k K) bool {
+	return s.shard(k).Contains(k)
+}
+
+// Delete deletes an entry from the cache. See Cache.Delete.
+func (s *Sharded[K, V]) Delete(k K) {
+	s.shard(k).Delete(k)
+}
+
+// GetSet tries to find k in the cache. See Cache.GetSet.
+func (s *Sharded[K, V]) GetSet(k K, v V) V {
+	return s.shard(k).GetSet(k, v)
+}
+
+// GetSetWithTTL is like GetSet, but if v is set it will be cached for ttl.
+// See Cache.GetSetWithTTL.
+func (s *Sharded[K, V]) GetSetWithTTL(k K, v V, ttl time.Duration) V {
+	return s.shard(k).GetSetWithTTL(k, v, ttl)
+}
+
+// GetOrLoad returns the value of k in the cache, loading it with load if it is
+// missing. See Cache.GetOrLoad.
+func (s *Sharded[K, V]) GetOrLoad(k K, load func(K) (V, error)) (V, error) {
+	return s.shard(k).GetOrLoad(k, load)
+}
+
+// Compute atomically updates the value of k. See Cache.Compute.
+func (s *Sharded[K, V]) Compute(k K, f func(old V, found bool) (V, ComputeOp)) (V, bool) {
+	return s.shard(k).Compute(k, f)
+}
+
+// Update atomically sets the value of k to the one returned by f, if k is
+// found in the cache. See Cache.Update.
+func (s *Sharded[K, V]) Update(k K, f func(old V) V) bool {
+	return s.shard(k).Update(k, f)
+}
+
+// SetIfAbsent sets v to k only if k is not in the cache. See Cache.SetIfAbsent.
+func (s *Sharded[K, V]) SetIfAbsent(k K, v V) bool {
+	return s.shard(k).SetIfAbsent(k, v)
+}
+
+// CompareAndSwap sets the value of k to new if its current value is equal to
+// old. See Cache.CompareAndSwap.
+func (s *Sharded[K, V]) CompareAndSwap(k K, old, new V) bool {
+	return s.shard(k).CompareAndSwap(k, old, new)
+}
+
+// CompareAndDelete deletes k if its value is equal to old.
+// See Cache.CompareAndDelete.
+func (s *Sharded[K, V]) CompareAndDelete(k K, old V) bool {
+	return s.shard(k).CompareAndDelete(k, old)
+}
+
+// group returns the indexes of keys, grouped by the index of their shard.
+func (s *Sharded[K, V]) group(keys []K) [][]int {
+	groups := make([][]int, len(s.shards))
+	for i, k := range keys {
+		n := hashKey(k) & s.mask
+		groups[n] = append(groups[n], i)
+	}
+	return groups
+}
+
+// pick returns the keys at the indexes idx.
+func pick[K any](keys []K, idx []int) []K {
+	picked := make([]K, len(idx))
+	for i, n := range idx {
+		picked[i] = keys[n]
+	}
+	return picked
+}
+
+// GetMany returns the values of keys in the cache, and whether each of them
+// was found. The keys of each shard are looked up under a single lock.
+// See Cache.GetMany.
+func (s *Sharded[K, V]) GetMany(keys []K) ([]V, []bool) {
+	vs := make([]V, len(keys))
+	found := make([]bool, len(keys))
+	for i, idx := range s.group(keys) {
+		if len(idx) == 0 {
+			continue
+		}
+		svs, sfound := s.shards[i].GetMany(pick(keys, idx))
+		for j, n := range idx {
+			vs[n], found[n] = svs[j], sfound[j]
+		}
+	}
+	return vs, found
+}
+
+// SetMany sets all entries of m to the cache. The entries of each shard are
+// set under a single lock. See Cache.SetMany.
+func (s *Sharded[K, V]) SetMany(m map[K]V) {
+	parts := make([]map[K]V, len(s.shards))
+	for k, v := range m {
+		n := hashKey(k) & s.mask
+		if parts[n] == nil {
+			parts[n] = make(map[K]V)
+		}
+		parts[n][k] = v
+	}
+	for i, part := range parts {
+		if part != nil {
+			s.shards[i].SetMany(part)
+		}
+	}
+}
+
+// DeleteMany deletes keys from the cache, reporting whether each of them was
+// found. The keys of each shard are deleted under a single lock.
+// See Cache.DeleteMany.
+func (s *Sharded[K, V]) DeleteMany(keys []K) []bool {
+	found := make([]bool, len(keys))
+	for i, idx := range s.group(keys) {
+		if len(idx) == 0 {
+			continue
+		}
+		sfound := s.shards[i].DeleteMany(pick(keys, idx))
+		for j, n := range idx {
+			found[n] = sfound[j]
+		}
+	}
+	return found
+}
+
+// GetOrLoadMany returns the values of keys in the cache, loading the missing
+// ones with load. See Cache.GetOrLoadMany.
+//
+// load is called once for each shard holding missing keys, with the missing
+// keys of that shard.
+func (s *Sharded[K, V]) GetOrLoadMany(keys []K, load func([]K) (map[K]V, error)) (map[K]V, error) {
+	var (
+		m        = make(map[K]V, len(keys))
+		firstErr error
+	)
+	for i, idx := range s.group(keys) {
+		if len(idx) == 0 {
+			continue
+		}
+		sm, err := s.shards[i].GetOrLoadMany(pick(keys, idx), load)
+		for k, v := range sm {
+			m[k] = v
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return m, firstErr
+}
+
+// InvalidateTag deletes all entries tagged with tag from every shard and
+// returns how many were deleted. See Cache.InvalidateTag.
+func (s *Sharded[K, V]) InvalidateTag(tag string) int {
+	var n int
+	for _, c := range s.shards {
+		n += c.InvalidateTag(tag)
+	}
+	return n
+}
+
+// Range calls f sequentially for each key and value present in the cache.
+// If f returns false, Range stops the iteration.
+//
+// Each shard is copied when Range gets to it, so f may use the cache, and it
+// will see the changes made to the shards Range did not get to yet.
+func (s *Sharded[K, V]) Range(f func(k K, v V) bool) {
+	for _, c := range s.shards {
+		stopped := false
+		c.Range(func(k K, v V) bool {
+			stopped = !f(k, v)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// Keys returns the keys present in the cache, in no particular order.
+func (s *Sharded[K, V]) Keys() []K {
+	var keys []K
+	for _, c := range s.shards {
+		keys = append(keys, c.Keys()...)
+	}
+	return keys
+}
+
+// Values returns the values present in the cache, in no particular order.
+func (s *Sharded[K, V]) Values() []V {
+	var values []V
+	for _, c := range s.shards {
+		values = append(values, c.Values()...)
+	}
+	return values
+}
+
+// Snapshot returns a copy of the entries present in the cache.
+//
+// Each shard is copied while it is locked, but the shards are copied one at a
+// time, so the copy is only consistent within each shard.
+func (s *Sharded[K, V]) Snapshot() map[K]V {
+	m := make(map[K]V)
+	for _, c := range s.shards {
+		for k, v := range c.Snapshot() {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Save writes the entries present in the cache to w, using codec. The entries
+// can be restored with Load, or with Cache.Load, and the entries saved by
+// Cache.Save can be loaded by Load. See Cache.Save.
+func (s *Sharded[K, V]) Save(w io.Writer, codec Codec) error {
+	var records []record[K, V]
+	for _, c := range s.shards {
+		records = c.records(records)
+	}
+	return saveRecords(w, codec, records)
+}
+
+// Load reads the entries written by Save from r, using codec, and sets them
+// to the cache with their remaining ttl. See Cache.Load.
+func (s *Sharded[K, V]) Load(r io.Reader, codec Codec) error {
+	return loadRecords(r, codec, s.shards[0].now, s.SetWithTTL)
+}
+
+// Stats returns the sum of the statistics of the shards. See Cache.Stats.
+func (s *Sharded[K, V]) Stats() Stats {
+	var st Stats
+	for _, c := range s.shards {
+		cs := c.Stats()
+		st.Hits += cs.Hits
+		st.Misses += cs.Misses
+		st.Sets += cs.Sets
+		st.Deletes += cs.Deletes
+		st.Expirations += cs.Expirations
+		st.Evictions += cs.Evictions
+		st.Size += cs.Size
+		st.Weight += cs.Weight
+		st.GCRuns += cs.GCRuns
+		st.GCDuration += cs.GCDuration
+	}
+	return st
+}
+
+// ResetStats sets all the counters returned by Stats to 0.
+func (s *Sharded[K, V]) ResetStats() {
+	for _, c := range s.shards {
+		c.ResetStats()
+	}
+}
+
+// Wipe deletes all entries from the cache.
+//
+// The shards are wiped one at a time, so concurrent operations may see some of
+// the shards wiped and others not.
+func (s *Sharded[K, V]) Wipe() {
+	for _, c := range s.shards {
+		c.Wipe()
+	}
+}
+
+// Len returns the len of the cache. See Cache.Len.
+func (s *Sharded[K, V]) Len() int {
+	var n int
+	for _, c := range s.shards {
+		n += c.Len()
+	}
+	return n
+}
+
+// Evictions returns how many entries were evicted from the shards because
+// they were full. See Cache.Evictions.
+func (s *Sharded[K, V]) Evictions() uint64 {
+	var n uint64
+	for _, c := range s.shards {
+		n += c.Evictions()
+	}
+	return n
+}
+
+// TickGC runs the GC of every shard now.
+func (s *Sharded[K, V]) TickGC() {
+	for _, c := range s.shards {
+		c.TickGC()
+	}
+}
+
+// Close closes every shard. See Cache.Close.
+func (s *Sharded[K, V]) Close() {
+	for _, c := range s.shards {
+		c.Close()
+	}
+}
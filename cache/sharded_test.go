@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+func TestSharded(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cacheFor time.Duration
+		f        func(*Sharded[string, int], *clock.Fake) error
+	}{
+		{
+			"get set delete",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				for i := 0; i < 1000; i++ {
+					s.Set(strconv.Itoa(i), i)
+				}
+
+				if s.Len() != 1000 {
+					return fmt.Errorf("cache len is not 1000, it is %d", s.Len())
+				}
+
+				for i := 0; i < 1000; i++ {
+					v, ok := s.Get(strconv.Itoa(i))
+					if !ok || v != i {
+						return fmt.Errorf("expected key %d to be %d but got %d, %t instead", i, i, v, ok)
+					}
+				}
+
+				s.Delete("0")
+				if s.Contains("0") {
+					return fmt.Errorf("key 0 found in cache")
+				}
+
+				if v := s.GetSet("1", -1); v != 1 {
+					return fmt.Errorf("expected key 1 to be 1 but got %d instead", v)
+				}
+
+				s.Wipe()
+				if s.Len() != 0 {
+					return fmt.Errorf("cache len is not 0")
+				}
+
+				return nil
+			},
+		},
+		{
+			"shards are spread",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				for i := 0; i < 1000; i++ {
+					s.Set(strconv.Itoa(i), i)
+				}
+
+				for i, c := range s.shards {
+					if c.Len() == 0 {
+						return fmt.Errorf("shard %d is empty", i)
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			"gc",
+			time.Minute,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				for i := 0; i < 100; i++ {
+					s.Set(strconv.Itoa(i), i)
+				}
+				clk.Advance(time.Minute + time.Second)
+				if !waitFor(func() bool { return s.Len() == 0 }) {
+					return fmt.Errorf("cache len is not 0, it is %d", s.Len())
+				}
+				return nil
+			},
+		},
+		{
+			"save and load",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				for i := 0; i < 100; i++ {
+					s.Set(strconv.Itoa(i), i)
+				}
+
+				var buf bytes.Buffer
+				if err := s.Save(&buf, GobCodec); err != nil {
+					return err
+				}
+
+				c := New[string, int](0)
+				defer c.Close()
+				if err := c.Load(&buf, GobCodec); err != nil {
+					return err
+				}
+				if c.Len() != 100 {
+					return fmt.Errorf("loaded %d entries instead of 100", c.Len())
+				}
+
+				s.Wipe()
+				if err := c.Save(&buf, GobCodec); err != nil {
+					return err
+				}
+				if err := s.Load(&buf, GobCodec); err != nil {
+					return err
+				}
+				if s.Len() != 100 {
+					return fmt.Errorf("loaded %d entries instead of 100", s.Len())
+				}
+
+				return nil
+			},
+		},
+		{
+			"compute",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				if !s.SetIfAbsent("foo", 1) || s.SetIfAbsent("foo", 2) {
+					return fmt.Errorf("SetIfAbsent did not set only the absent key")
+				}
+
+				if !s.Update("foo", func(old int) int { return old + 1 }) {
+					return fmt.Errorf("Update did not find key foo")
+				}
+
+				if !s.CompareAndSwap("foo", 2, 3) || s.CompareAndSwap("foo", 2, 4) {
+					return fmt.Errorf("CompareAndSwap did not swap only the matching value")
+				}
+
+				v, ok := s.Compute("foo", func(old int, found bool) (int, ComputeOp) {
+					return old * 2, ComputeSet
+				})
+				if !ok || v != 6 {
+					return fmt.Errorf("expected Compute to set 6 but got %d, %t instead", v, ok)
+				}
+
+				if !s.CompareAndDelete("foo", 6) || s.Contains("foo") {
+					return fmt.Errorf("CompareAndDelete did not delete key foo")
+				}
+
+				return nil
+			},
+		},
+		{
+			"concurrent reads and writes",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				wg := new(sync.WaitGroup)
+				wg.Add(200)
+				for i := 0; i < 100; i++ {
+					go func(i int) {
+						defer wg.Done()
+						s.Set(strconv.Itoa(i), i)
+					}(i)
+
+					go func(i int) {
+						defer wg.Done()
+						s.Get(strconv.Itoa(i))
+					}(i)
+				}
+				wg.Wait()
+				return nil
+			},
+		},
+		{
+			"batch",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				m := make(map[string]int)
+				for i := 0; i < 100; i++ {
+					m[strconv.Itoa(i)] = i
+				}
+				s.SetMany(m)
+
+				keys := []string{"0", "foo", "99", "50"}
+				vs, found := s.GetMany(keys)
+				if fmt.Sprint(vs, found) != "[0 0 99 50] [true false true true]" {
+					return fmt.Errorf("unexpected GetMany result: %v, %v", vs, found)
+				}
+
+				var loaded []string
+				got, err := s.GetOrLoadMany(keys, func(keys []string) (map[string]int, error) {
+					loaded = append(loaded, keys...)
+					return map[string]int{"foo": -1}, nil
+				})
+				if err != nil || len(got) != 4 || got["foo"] != -1 || fmt.Sprint(loaded) != "[foo]" {
+					return fmt.Errorf("unexpected GetOrLoadMany result: %v, %v, loaded %v", got, err, loaded)
+				}
+
+				if found := s.DeleteMany(keys); fmt.Sprint(found) != "[true true true true]" {
+					return fmt.Errorf("unexpected DeleteMany result: %v", found)
+				}
+
+				if s.Len() != 97 {
+					return fmt.Errorf("cache len is not 97, it is %d", s.Len())
+				}
+
+				return nil
+			},
+		},
+		{
+			"tags",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				for i := 0; i < 100; i++ {
+					s.SetWithTags(strconv.Itoa(i), i, "all")
+				}
+				s.Set("foo", 1)
+
+				if n := s.InvalidateTag("all"); n != 100 {
+					return fmt.Errorf("expected 100 entries to be invalidated but got %d instead", n)
+				}
+
+				if s.Len() != 1 || !s.Contains("foo") {
+					return fmt.Errorf("expected only key foo to be left in the cache")
+				}
+
+				return nil
+			},
+		},
+		{
+			"iteration and stats",
+			0,
+			func(s *Sharded[string, int], clk *clock.Fake) error {
+				for i := 0; i < 100; i++ {
+					s.Set(strconv.Itoa(i), i)
+				}
+				s.SetWithWeight("0", 0, 5)
+				s.Get("0")
+				s.Get("foo")
+
+				var sum, n int
+				s.Range(func(k string, v int) bool {
+					sum += v
+					n++
+					return n < 50
+				})
+				if n != 50 {
+					return fmt.Errorf("Range did not stop, it iterated over %d entries", n)
+				}
+
+				if len(s.Keys()) != 100 || len(s.Values()) != 100 || len(s.Snapshot()) != 100 {
+					return fmt.Errorf("expected 100 keys, values and snapshot entries")
+				}
+
+				st := s.Stats()
+				if st.Size != 100 || st.Sets != 101 || st.Hits != 1 || st.Misses != 1 || st.Weight != 104 {
+					return fmt.Errorf("unexpected stats: %+v", st)
+				}
+
+				s.ResetStats()
+				if st := s.Stats(); st.Sets != 0 {
+					return fmt.Errorf("stats were not reset: %+v", st)
+				}
+
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			s := NewSharded(6, tc.cacheFor, WithClock[string, int](clk))
+			defer s.Close()
+			if len(s.shards) != 8 {
+				t.Fatalf("\nexpected 8 shards but got %d instead", len(s.shards))
+			}
+			if err := tc.f(s, clk); err != nil {
+				t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestShardedPolicy(t *testing.T) {
+	s := NewSharded(4, 0,
+		WithCapacity[string, int](10),
+		WithPolicyFunc[string, int](NewLFU[string]),
+	)
+	defer s.Close()
+	for i := 1; i < len(s.shards); i++ {
+		if s.shards[i].policy == s.shards[0].policy {
+			t.Errorf("\nshards 0 and %d share their policy", i)
+		}
+	}
+}
+
+const benchKeys = 1 << 16
+
+var benchKeyNames = func() []string {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	return keys
+}()
+
+type benchCache interface {
+	Set(string, int)
+	Get(string) (int, bool)
+}
+
+// benchmarkParallel runs a mix of 90% reads and 10% writes.
+func benchmarkParallel(b *testing.B, c benchCache) {
+	for i, k := range benchKeyNames {
+		c.Set(k, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		i := 0
+		for p.Next() {
+			k := benchKeyNames[(i*7919)&(benchKeys-1)]
+			if i%10 == 0 {
+				c.Set(k, i)
+			} else {
+				c.Get(k)
+			}
+			i++
+		}
+	})
+}
+
+func Benchmark_Cache_Parallel(b *testing.B) {
+	c := New[string, int](time.Minute)
+	defer c.Close()
+	benchmarkParallel(b, c)
+}
+
+func Benchmark_Sharded_Parallel(b *testing.B) {
+	s := NewSharded[string, int](64, time.Minute)
+	defer s.Close()
+	benchmarkParallel(b, s)
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the statistics of a Cache, as returned by Cache.Stats.
+type Stats struct {
+	// Hits is how many reads found their key.
+	Hits uint64
+	// Misses is how many reads did not find their key.
+	Misses uint64
+	// Sets is how many entries were set.
+	Sets uint64
+	// Deletes is how many entries were deleted with Delete or Wipe.
+	Deletes uint64
+	// Expirations is how many expired entries were deleted.
+	Expirations uint64
+	// Evictions is how many entries were evicted because the cache was full.
+	Evictions uint64
+	// Size is the amount of entries in the cache. See Cache.Len.
+	Size int
+	// Weight is the total weight of the entries in the cache.
+	// See WithMaxWeight.
+	Weight int64
+	// GCRuns is how many times the GC ran, be it by ticking or by TickGC.
+	GCRuns uint64
+	// GCDuration is the total time spent by the GC runs.
+	GCDuration time.Duration
+}
+
+type stats struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	expirations atomic.Uint64
+	evictions   atomic.Uint64
+	gcRuns      atomic.Uint64
+	gcNanos     atomic.Int64
+}
+
+func (s *stats) hit(ok bool) {
+	if ok {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+func (s *stats) gc(d time.Duration) {
+	s.gcRuns.Add(1)
+	s.gcNanos.Add(int64(d))
+}
+
+func (s *stats) removed(reason EvictReason) {
+	switch reason {
+	case ReasonExpired:
+		s.expirations.Add(1)
+	case ReasonDeleted, ReasonWiped:
+		s.deletes.Add(1)
+	case ReasonCapacity:
+		s.evictions.Add(1)
+	}
+}
+
+// Stats returns the statistics of the cache since it was created or since the
+// last call to ResetStats.
+//
+// The counters are updated atomically without locking the cache, so a Stats
+// taken while the cache is in use is not necessarily consistent between its
+// fields.
+func (c *Cache[K, V]) Stats() Stats {
+	c.locker.RLock()
+	size, weight := len(c.cache), c.weight
+	c.locker.RUnlock()
+
+	return Stats{
+		Hits:        c.stats.hits.Load(),
+		Misses:      c.stats.misses.Load(),
+		Sets:        c.stats.sets.Load(),
+		Deletes:     c.stats.deletes.Load(),
+		Expirations: c.stats.expirations.Load(),
+		Evictions:   c.stats.evictions.Load(),
+		Size:        size,
+		Weight:      weight,
+		GCRuns:      c.stats.gcRuns.Load(),
+		GCDuration:  time.Duration(c.stats.gcNanos.Load()),
+	}
+}
+
+// ResetStats sets all the counters returned by Stats to 0.
+func (c *Cache[K, V]) ResetStats() {
+	c.stats.hits.Store(0)
+	c.stats.misses.Store(0)
+	c.stats.sets.Store(0)
+	c.stats.deletes.Store(0)
+	c.stats.expirations.Store(0)
+	c.stats.evictions.Store(0)
+	c.stats.gcRuns.Store(0)
+	c.stats.gcNanos.Store(0)
+}
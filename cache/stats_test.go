@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+func TestCacheStats(t *testing.T) {
+	c := New(
+		time.Hour,
+		WithCapacity[string, int](2),
+		WithClock[string, int](clock.NewFake(time.Now())),
+	)
+	defer c.Close()
+
+	c.Set("foo", 1)
+	c.Set("bar", 2)
+	c.Get("foo")
+	c.Get("baz")
+	c.GetSet("foo", 3)
+	c.GetSet("baz", 3)
+	setExpired(c, "foo", 1)
+	c.Get("foo")
+	c.Set("qux", 4)
+	c.Delete("qux")
+	c.Delete("qux")
+	c.Set("foo", 1)
+	c.Wipe()
+	c.Set("foo", 1)
+	c.TickGC()
+
+	want := Stats{
+		Hits:        2,
+		Misses:      3,
+		Sets:        7,
+		Deletes:     3,
+		Expirations: 1,
+		Evictions:   1,
+		Size:        1,
+		Weight:      1,
+		GCRuns:      1,
+	}
+	if got := c.Stats(); got != want {
+		t.Errorf("\nwant: %+v\ngot: %+v", want, got)
+	}
+
+	c.ResetStats()
+	want = Stats{Size: 1, Weight: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("\nwant: %+v\ngot: %+v", want, got)
+	}
+}
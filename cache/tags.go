@@ -0,0 +1,75 @@
+package cache
+
+import "slices"
+
+// SetWithTags sets a new value to the Cache cache, like Set, and tags it with
+// tags, so that it can be deleted along with the other entries sharing any of
+// them with InvalidateTag. Empty tags are ignored.
+//
+// Setting k again, with or without tags, replaces its tags.
+func (c *Cache[K, V]) SetWithTags(k K, v V, tags ...string) {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+	c.set(k, v, c.cacheFor)
+
+	it, ok := c.cache[k]
+	if !ok {
+		return
+	}
+
+	for _, tag := range tags {
+		if tag == "" || slices.Contains(it.tags, tag) {
+			continue
+		}
+		it.tags = append(it.tags, tag)
+
+		if c.tags == nil {
+			c.tags = make(map[string]map[K]struct{})
+		}
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			c.tags[tag] = keys
+		}
+		keys[k] = struct{}{}
+	}
+}
+
+// InvalidateTag deletes all entries tagged with tag and returns how many were
+// deleted.
+//
+// If the cache uses an invalidation Bus, the entries tagged with tag are also
+// deleted from the other caches on the bus.
+func (c *Cache[K, V]) InvalidateTag(tag string) int {
+	n := c.invalidateTagLocal(tag)
+	if tag != "" {
+		c.publish(Invalidation{Tag: tag})
+	}
+	return n
+}
+
+func (c *Cache[K, V]) invalidateTagLocal(tag string) int {
+	c.locker.Lock()
+	defer c.unlockAndNotify()
+
+	keys := c.tags[tag]
+	n := len(keys)
+	for k := range keys {
+		c.delete(k, ReasonDeleted)
+	}
+	return n
+}
+
+// untag removes it from the index of its tags.
+//
+// untag must be called with c.locker held for writing.
+func (c *Cache[K, V]) untag(it *item[K, V]) {
+	for _, tag := range it.tags {
+		keys := c.tags[tag]
+		delete(keys, it.k)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	it.tags = nil
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCacheTags(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    func(*Cache[string, int]) error
+	}{
+		{
+			"invalidate tag",
+			func(c *Cache[string, int]) error {
+				c.SetWithTags("foo", 1, "a", "b")
+				c.SetWithTags("bar", 2, "a")
+				c.SetWithTags("baz", 3, "b")
+				c.Set("qux", 4)
+
+				if n := c.InvalidateTag("a"); n != 2 {
+					return fmt.Errorf("expected 2 entries to be deleted but got %d instead", n)
+				}
+
+				if c.Contains("foo") || c.Contains("bar") {
+					return fmt.Errorf("tagged entries found in cache")
+				}
+
+				if !c.Contains("baz") || !c.Contains("qux") {
+					return fmt.Errorf("untagged entries not found in cache")
+				}
+
+				if n := c.InvalidateTag("b"); n != 1 {
+					return fmt.Errorf("expected 1 entry to be deleted but got %d instead", n)
+				}
+
+				if n := c.InvalidateTag("c"); n != 0 {
+					return fmt.Errorf("expected no entry to be deleted but got %d instead", n)
+				}
+
+				return nil
+			},
+		},
+		{
+			"set replaces tags",
+			func(c *Cache[string, int]) error {
+				c.SetWithTags("foo", 1, "a")
+				c.SetWithTags("foo", 2, "b", "b")
+				c.SetWithTags("bar", 1, "a")
+				c.Set("bar", 2)
+
+				if n := c.InvalidateTag("a"); n != 0 {
+					return fmt.Errorf("expected no entry to be deleted but got %d instead", n)
+				}
+
+				if n := c.InvalidateTag("b"); n != 1 {
+					return fmt.Errorf("expected 1 entry to be deleted but got %d instead", n)
+				}
+
+				if len(c.tags) != 0 {
+					return fmt.Errorf("expected no tags left but got %v instead", c.tags)
+				}
+
+				return nil
+			},
+		},
+		{
+			"removed entries are untagged",
+			func(c *Cache[string, int]) error {
+				c.SetWithTags("foo", 1, "a")
+				c.SetWithTags("bar", 2, "a")
+				setExpired(c, "baz", 3)
+				c.SetWithTags("baz", 3, "a")
+				setExpired(c, "baz", 3)
+				c.Delete("foo")
+				c.TickGC()
+
+				if n := c.InvalidateTag("a"); n != 1 {
+					return fmt.Errorf("expected 1 entry to be deleted but got %d instead", n)
+				}
+
+				c.SetWithTags("foo", 1, "a")
+				c.Wipe()
+				if len(c.tags) != 0 {
+					return fmt.Errorf("expected no tags left but got %v instead", c.tags)
+				}
+
+				return nil
+			},
+		},
+	} {
+		c := New[string, int](0)
+		if err := tc.f(c); err != nil {
+			t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+		}
+		c.Close()
+	}
+}
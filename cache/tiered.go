@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Backend is a store used as the second level of a Tiered cache, usually
+// slower and bigger than a Cache, such as a remote cache or a disk.
+//
+// A Backend must be safe for concurrent use.
+type Backend[K comparable, V any] interface {
+	// Get returns the value of k, how long until it expires and if it was
+	// found. A ttl of 0 means the value never expires.
+	Get(ctx context.Context, k K) (v V, ttl time.Duration, ok bool, err error)
+
+	// Set stores v as the value of k for ttl. A ttl of 0 stores v forever.
+	Set(ctx context.Context, k K, v V, ttl time.Duration) error
+
+	// Delete deletes k. Deleting a missing key is not an error.
+	Delete(ctx context.Context, k K) error
+}
+
+// NewTiered creates a new Tiered cache using l1 as its first level and l2 as
+// its second level.
+func NewTiered[K comparable, V any](l1 *Cache[K, V], l2 Backend[K, V]) *Tiered[K, V] {
+	return &Tiered[K, V]{l1, l2}
+}
+
+// Tiered is a two level cache. Reads look for keys in the first level, a
+// Cache, and then in the second level, a Backend. Writes go to both levels.
+type Tiered[K comparable, V any] struct {
+	l1 *Cache[K, V]
+	l2 Backend[K, V]
+}
+
+// Get returns the value of k and if it was found.
+//
+// If k is not found in the first level, it is read from the second one and,
+// if found there, set to the first level until it expires in the second one,
+// or for the cacheFor of the Cache if that is sooner.
+func (t *Tiered[K, V]) Get(ctx context.Context, k K) (V, bool, error) {
+	if v, ok := t.l1.Get(k); ok {
+		return v, true, nil
+	}
+
+	v, ttl, ok, err := t.l2.Get(ctx, k)
+	if err != nil || !ok {
+		return v, false, err
+	}
+
+	if cacheFor := t.l1.cacheFor; cacheFor > 0 && (ttl == 0 || ttl > cacheFor) {
+		ttl = cacheFor
+	}
+	t.l1.SetWithTTL(k, v, ttl)
+	return v, true, nil
+}
+
+// Set sets v as the value of k in both levels, for the cacheFor of the Cache.
+func (t *Tiered[K, V]) Set(ctx context.Context, k K, v V) error {
+	return t.SetWithTTL(ctx, k, v, t.l1.cacheFor)
+}
+
+// SetWithTTL sets v as the value of k in both levels, for ttl. A ttl of 0
+// caches v forever.
+//
+// v is first set to the second level, and it is only set to the first level if
+// that succeeds.
+func (t *Tiered[K, V]) SetWithTTL(ctx context.Context, k K, v V, ttl time.Duration) error {
+	if err := t.l2.Set(ctx, k, v, ttl); err != nil {
+		return err
+	}
+	t.l1.SetWithTTL(k, v, ttl)
+	return nil
+}
+
+// Delete deletes k from both levels.
+//
+// k is first deleted from the second level, so that a Get running after it is
+// deleted from the first level can not find it in the second one and promote
+// it again.
+func (t *Tiered[K, V]) Delete(ctx context.Context, k K) error {
+	err := t.l2.Delete(ctx, k)
+	t.l1.Delete(k)
+	return err
+}
+
+// L1 returns the first level of the cache.
+func (t *Tiered[K, V]) L1() *Cache[K, V] {
+	return t.l1
+}
+
+// L2 returns the second level of the cache.
+func (t *Tiered[K, V]) L2() Backend[K, V] {
+	return t.l2
+}
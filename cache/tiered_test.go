@@ -0,0 +1,299 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+
+	for _, backend := range []struct {
+		name string
+		new  func(*testing.T, *clock.Fake) Backend[string, int]
+	}{
+		{
+			"memory",
+			func(t *testing.T, clk *clock.Fake) Backend[string, int] {
+				c := New(0, WithClock[string, int](clk))
+				t.Cleanup(c.Close)
+				return NewMemoryBackend(c)
+			},
+		},
+		{
+			"file",
+			func(t *testing.T, clk *clock.Fake) Backend[string, int] {
+				b, err := NewFileBackend(
+					filepath.Join(t.TempDir(), "cache"),
+					JSONCodec,
+					WithBackendClock[string, int](clk),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return b
+			},
+		},
+	} {
+		for _, tc := range []struct {
+			name string
+			f    func(*Tiered[string, int], *clock.Fake) error
+		}{
+			{
+				"writes through",
+				func(tr *Tiered[string, int], clk *clock.Fake) error {
+					if err := tr.Set(ctx, "foo", 1); err != nil {
+						return err
+					}
+
+					if v, ok := tr.L1().Get("foo"); !ok || v != 1 {
+						return fmt.Errorf("expected 1 in l1 but got %d, %t instead", v, ok)
+					}
+
+					if v, _, ok, err := tr.L2().Get(ctx, "foo"); err != nil || !ok || v != 1 {
+						return fmt.Errorf("expected 1 in l2 but got %d, %t, %v instead", v, ok, err)
+					}
+
+					return nil
+				},
+			},
+			{
+				"reads through and promotes",
+				func(tr *Tiered[string, int], clk *clock.Fake) error {
+					if err := tr.L2().Set(ctx, "foo", 1, 0); err != nil {
+						return err
+					}
+
+					if v, ok, err := tr.Get(ctx, "foo"); err != nil || !ok || v != 1 {
+						return fmt.Errorf("expected 1 but got %d, %t, %v instead", v, ok, err)
+					}
+
+					if v, ok := tr.L1().Get("foo"); !ok || v != 1 {
+						return fmt.Errorf("foo was not promoted to l1")
+					}
+
+					if _, ok, err := tr.Get(ctx, "bar"); err != nil || ok {
+						return fmt.Errorf("key bar found in cache")
+					}
+
+					return nil
+				},
+			},
+			{
+				"delete",
+				func(tr *Tiered[string, int], clk *clock.Fake) error {
+					if err := tr.Set(ctx, "foo", 1); err != nil {
+						return err
+					}
+
+					if err := tr.Delete(ctx, "foo"); err != nil {
+						return err
+					}
+
+					if err := tr.Delete(ctx, "foo"); err != nil {
+						return err
+					}
+
+					if _, ok, err := tr.Get(ctx, "foo"); err != nil || ok {
+						return fmt.Errorf("key foo found in cache")
+					}
+
+					return nil
+				},
+			},
+			{
+				"ttl",
+				func(tr *Tiered[string, int], clk *clock.Fake) error {
+					if err := tr.SetWithTTL(ctx, "foo", 1, 20*time.Millisecond); err != nil {
+						return err
+					}
+
+					if _, ttl, ok, err := tr.L2().Get(ctx, "foo"); err != nil || !ok || ttl <= 0 || ttl > 20*time.Millisecond {
+						return fmt.Errorf("expected foo to expire in 20ms but got %v, %t, %v instead", ttl, ok, err)
+					}
+
+					clk.Advance(30 * time.Millisecond)
+					if _, _, ok, err := tr.L2().Get(ctx, "foo"); err != nil || ok {
+						return fmt.Errorf("expired key foo found in l2")
+					}
+
+					return nil
+				},
+			},
+		} {
+			name := backend.name + " " + tc.name
+			t.Run(name, func(t *testing.T) {
+				clk := clock.NewFake(time.Now())
+				l1 := New(time.Hour, WithClock[string, int](clk))
+				defer l1.Close()
+				if err := tc.f(NewTiered(l1, backend.new(t, clk)), clk); err != nil {
+					t.Errorf("\ntest '%s' failed\nerr: %v", name, err)
+				}
+			})
+		}
+	}
+}
+
+func TestFileBackendPrune(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Now())
+	b, err := NewFileBackend(dir, GobCodec, WithBackendClock[string, int](clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Set(ctx, "foo", 1, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set(ctx, "bar", 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(5 * time.Millisecond)
+
+	if err := b.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("\nexpected 1 entry file but got %d instead", len(entries))
+	}
+
+	if v, _, ok, err := b.Get(ctx, "bar"); err != nil || !ok || v != 2 {
+		t.Errorf("\nexpected 2 but got %d, %t, %v instead", v, ok, err)
+	}
+}
+
+func TestTieredPromotionTTL(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	l1 := New(time.Hour, WithClock[string, int](clk))
+	defer l1.Close()
+	l2 := New(time.Hour, WithClock[string, int](clk))
+	defer l2.Close()
+	tr := NewTiered(l1, NewMemoryBackend(l2))
+
+	if err := tr.SetWithTTL(ctx, "foo", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	l1.Delete("foo")
+
+	if v, ok, err := tr.Get(ctx, "foo"); err != nil || !ok || v != 1 {
+		t.Fatalf("\nexpected 1 but got %d, %t, %v instead", v, ok, err)
+	}
+	if !l1.Contains("foo") {
+		t.Fatalf("\nfoo was not promoted to l1")
+	}
+
+	clk.Advance(time.Minute)
+	if _, ok, err := tr.Get(ctx, "foo"); err != nil || ok {
+		t.Errorf("\npromoted key foo outlived its ttl")
+	}
+}
+
+func TestFileBackendKeys(t *testing.T) {
+	type key struct {
+		ID   int
+		Name string
+	}
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, err := NewFileBackend[key, int](dir, GobCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The path of a key does not depend on the types gob saw before it, nor
+	// on the run of the program.
+	path := b.path(key{1, "foo"})
+	if want := filepath.Join(dir, "d32daee8edac5a74933aaec0a158bd1ec9387d5db45f337aac7d265f64571c39.entry"); path != want {
+		t.Errorf("\nwant: %s\ngot: %s", want, path)
+	}
+
+	if err := b.Set(ctx, key{1, "foo"}, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, ok, err := b.Get(ctx, key{1, "foo"}); err != nil || !ok || v != 1 {
+		t.Errorf("\nexpected 1 but got %d, %t, %v instead", v, ok, err)
+	}
+
+	type unexported struct {
+		id   int
+		name string
+	}
+	ub, err := NewFileBackend[unexported, int](dir, GobCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ub.path(unexported{1, "foo"}) == ub.path(unexported{2, "foo"}) {
+		t.Errorf("\nkeys with different unexported fields share a file")
+	}
+
+	ab, err := NewFileBackend[any, int](dir, GobCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, keys := range [][2]any{
+		{1, int64(1)},
+		{1 + 2i, 1 + 3i},
+		{[2]string{"ab", "c"}, [2]string{"a", "bc"}},
+	} {
+		if ab.path(keys[0]) == ab.path(keys[1]) {
+			t.Errorf("\nkeys %v and %v share a file", keys[0], keys[1])
+		}
+	}
+	if ab.path(0.0) != ab.path(math.Copysign(0, -1)) {
+		t.Errorf("\nequal keys +0 and -0 do not share a file")
+	}
+}
+
+func TestFileBackendRemoveExpired(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Now())
+	b, err := NewFileBackend(dir, JSONCodec, WithBackendClock[string, int](clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := b.path("foo")
+
+	// An entry that replaced the expired one before it was removed is kept.
+	if err := b.Set(ctx, "foo", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.removeExpired(path); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, ok, err := b.Get(ctx, "foo"); err != nil || !ok || v != 1 {
+		t.Errorf("\nexpected 1 but got %d, %t, %v instead", v, ok, err)
+	}
+
+	if err := b.Set(ctx, "foo", 1, time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Millisecond)
+	if err := b.removeExpired(path); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("\nexpected no files but got %d instead", len(entries))
+	}
+}
@@ -0,0 +1,209 @@
+package cache
+
+// NewTinyLFU returns a Policy implementing W-TinyLFU
+// (https://arxiv.org/abs/1512.00727), sized for a cache of capacity entries.
+//
+// New keys are admitted into a small LRU window. Keys leaving the window only
+// make it into the main segmented LRU if they were used more often than the
+// key they would replace, which keeps keys that are used a single time from
+// flushing the frequently used ones.
+func NewTinyLFU[K comparable](capacity int) Policy[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	windowCap := max(1, capacity/100)
+	mainCap := capacity - windowCap
+	t := &tinyLFU[K]{
+		entries:      make(map[K]tinyLFUEntry[K]),
+		sketch:       newSketch(capacity),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+	}
+	t.window.init()
+	t.probation.init()
+	t.protected.init()
+	return t
+}
+
+type tinyLFU[K comparable] struct {
+	entries map[K]tinyLFUEntry[K]
+	sketch  *sketch
+
+	window    list[K]
+	probation list[K]
+	protected list[K]
+
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+type segment uint8
+
+const (
+	segWindow segment = iota
+	segProbation
+	segProtected
+)
+
+type tinyLFUEntry[K comparable] struct {
+	n   *node[K]
+	seg segment
+}
+
+func (t *tinyLFU[K]) Add(k K) {
+	if _, ok := t.entries[k]; ok {
+		t.Access(k)
+		return
+	}
+	t.sketch.increment(hashKey(k))
+	t.entries[k] = tinyLFUEntry[K]{t.window.pushFront(k), segWindow}
+}
+
+func (t *tinyLFU[K]) Access(k K) {
+	e, ok := t.entries[k]
+	if !ok {
+		return
+	}
+	t.sketch.increment(hashKey(k))
+
+	switch e.seg {
+	case segWindow:
+		t.window.moveToFront(e.n)
+	case segProbation:
+		t.probation.remove(e.n)
+		t.entries[k] = tinyLFUEntry[K]{t.protected.pushFront(k), segProtected}
+		if t.protected.len > t.protectedCap {
+			demoted := t.protected.back()
+			t.protected.remove(demoted)
+			t.entries[demoted.k] = tinyLFUEntry[K]{t.probation.pushFront(demoted.k), segProbation}
+		}
+	case segProtected:
+		t.protected.moveToFront(e.n)
+	}
+}
+
+func (t *tinyLFU[K]) Remove(k K) {
+	if e, ok := t.entries[k]; ok {
+		t.segment(e.seg).remove(e.n)
+		delete(t.entries, k)
+	}
+}
+
+func (t *tinyLFU[K]) Evict() (K, bool) {
+	for t.window.len > t.windowCap && t.mainLen() < t.mainCap {
+		t.toProbation(t.window.back())
+	}
+
+	if t.window.len > t.windowCap {
+		candidate := t.window.back()
+		victim := t.mainBack()
+		if victim != nil && t.sketch.estimate(hashKey(candidate.k)) > t.sketch.estimate(hashKey(victim.k)) {
+			t.toProbation(candidate)
+			return t.forget(victim), true
+		}
+		return t.forget(candidate), true
+	}
+
+	if victim := t.mainBack(); victim != nil {
+		return t.forget(victim), true
+	}
+
+	if victim := t.window.back(); victim != nil {
+		return t.forget(victim), true
+	}
+
+	var zero K
+	return zero, false
+}
+
+func (t *tinyLFU[K]) segment(seg segment) *list[K] {
+	switch seg {
+	case segProbation:
+		return &t.probation
+	case segProtected:
+		return &t.protected
+	default:
+		return &t.window
+	}
+}
+
+func (t *tinyLFU[K]) mainLen() int {
+	return t.probation.len + t.protected.len
+}
+
+// mainBack returns the next victim of the main segmented LRU.
+func (t *tinyLFU[K]) mainBack() *node[K] {
+	if n := t.probation.back(); n != nil {
+		return n
+	}
+	return t.protected.back()
+}
+
+// toProbation moves n from the window to the probation segment.
+func (t *tinyLFU[K]) toProbation(n *node[K]) {
+	t.window.remove(n)
+	t.entries[n.k] = tinyLFUEntry[K]{t.probation.pushFront(n.k), segProbation}
+}
+
+func (t *tinyLFU[K]) forget(n *node[K]) K {
+	t.Remove(n.k)
+	return n.k
+}
+
+// sketch is a count-min sketch with 4 bit counters, used to estimate how
+// often a key was used. Counters are halved periodically so that old
+// popularity fades away.
+type sketch struct {
+	rows      [4][]uint8
+	mask      uint32
+	additions int
+	resetAt   int
+}
+
+func newSketch(capacity int) *sketch {
+	// 4 counters per row for each entry keeps collisions between keys rare.
+	width := 16
+	for width < 4*capacity {
+		width <<= 1
+	}
+	s := &sketch{mask: uint32(width - 1), resetAt: 10 * capacity}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *sketch) increment(h uint64) {
+	h1, h2 := uint32(h), uint32(h>>32)
+	for i := range s.rows {
+		idx := (h1 + uint32(i)*h2) & s.mask
+		if s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *sketch) estimate(h uint64) uint8 {
+	h1, h2 := uint32(h), uint32(h>>32)
+	est := uint8(15)
+	for i := range s.rows {
+		est = min(est, s.rows[i][(h1+uint32(i)*h2)&s.mask])
+	}
+	return est
+}
+
+func (s *sketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -2,13 +2,17 @@ package httputils
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
+
+	"github.com/phenpessoa/gutils/ratelimit"
 )
 
 // ChiLogger is a middleware used to log requests
@@ -42,6 +46,30 @@ func ChiLogger(l *zap.Logger) func(next http.Handler) http.Handler {
 	}
 }
 
+// ChiRateLimiter is a middleware used to rate limit requests
+// per user IP with l. The IP is read like ReadUserIP does, but a
+// RemoteAddr without a port, as set by chi's middleware.RealIP,
+// is accepted too.
+// Requests over the limit are answered with 429 Too Many Requests
+// and a Retry-After header, without calling next.
+func ChiRateLimiter(l ratelimit.Limiter[string]) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ok, retryAfter := l.Allow(clientIP(r))
+			if !ok {
+				secs := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
 // ReadUserIP returns the user IP of the request
 func ReadUserIP(r *http.Request) string {
 	ip, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
@@ -57,6 +85,22 @@ func ReadUserIP(r *http.Request) string {
 	return remoteIP.String()
 }
 
+// clientIP returns the user IP of the request. If RemoteAddr has no
+// port, it is parsed as a bare IP, and if it is not an IP at all it
+// is returned as is, so that different clients never share a key.
+func clientIP(r *http.Request) string {
+	if ip := ReadUserIP(r); ip != "" {
+		return ip
+	}
+
+	addr := strings.TrimSpace(r.RemoteAddr)
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+
+	return addr
+}
+
 // Terminal collor codes
 const (
 	green   = "\033[97;42m"
@@ -5,9 +5,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/phenpessoa/gutils/ratelimit"
 )
 
 func TestChiLogger(t *testing.T) {
@@ -39,6 +43,71 @@ func TestChiLogger(t *testing.T) {
 	}
 }
 
+func TestChiRateLimiter(t *testing.T) {
+	l := ratelimit.NewGCRA[string](time.Minute, 2)
+	defer l.Close()
+
+	handler := ChiRateLimiter(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+
+	for i, tc := range []struct {
+		remoteAddr string
+		code       int
+		retryAfter string
+	}{
+		{"127.0.0.1:8080", http.StatusOK, ""},
+		{"127.0.0.1:8081", http.StatusOK, ""},
+		{"127.0.0.1:8082", http.StatusTooManyRequests, "60"},
+		{"127.0.0.2:8080", http.StatusOK, ""},
+		{"1.2.3.4", http.StatusOK, ""},
+		{"1.2.3.4", http.StatusOK, ""},
+		{"1.2.3.4", http.StatusTooManyRequests, "60"},
+		{"1.2.3.5", http.StatusOK, ""},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+		req.RemoteAddr = tc.remoteAddr
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tc.code {
+			t.Errorf("\nrequest %d failed\nwant: %d\ngot: %d", i, tc.code, w.Code)
+		}
+
+		if got := w.Header().Get("Retry-After"); got != tc.retryAfter {
+			t.Errorf("\nrequest %d failed\nwant Retry-After: %q\ngot: %q", i, tc.retryAfter, got)
+		}
+	}
+}
+
+func TestChiRateLimiterRealIP(t *testing.T) {
+	l := ratelimit.NewGCRA[string](time.Minute, 1)
+	defer l.Close()
+
+	handler := middleware.RealIP(ChiRateLimiter(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	})))
+
+	for i, tc := range []struct {
+		realIP string
+		code   int
+	}{
+		{"1.2.3.4", http.StatusOK},
+		{"1.2.3.5", http.StatusOK},
+		{"1.2.3.4", http.StatusTooManyRequests},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+		req.RemoteAddr = "10.0.0.1:8080"
+		req.Header.Set("X-Real-IP", tc.realIP)
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tc.code {
+			t.Errorf("\nrequest %d failed\nwant: %d\ngot: %d", i, tc.code, w.Code)
+		}
+	}
+}
+
 func TestReadUserIP(t *testing.T) {
 	for _, tc := range []struct {
 		name string
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/phenpessoa/gutils/cache"
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// NewGCRA returns a GCRA allowing an event of each key every every, with bursts
+// of up to burst events. It panics if every or burst are not positive.
+func NewGCRA[K comparable](every time.Duration, burst int, opts ...Option) *GCRA[K] {
+	if every <= 0 || burst <= 0 {
+		panic("ratelimit: non-positive rate or burst for NewGCRA")
+	}
+
+	// A key idle for this long has a theoretical arrival time in the past,
+	// like a new one.
+	c, clk := newCache[K, int64](time.Duration(burst)*every, opts)
+	return &GCRA[K]{c, clk, int64(every), int64(burst) * int64(every)}
+}
+
+// GCRA is a Limiter implementing the generic cell rate algorithm. It behaves
+// like a TokenBucket, but only keeps the theoretical arrival time of the next
+// event of each key.
+type GCRA[K comparable] struct {
+	c     *cache.Cache[K, int64]
+	clock clock.Clock
+	every int64
+	// limit is how far ahead of now the theoretical arrival time may be.
+	limit int64
+}
+
+// Allow implements Limiter.
+func (l *GCRA[K]) Allow(k K) (bool, time.Duration) {
+	now := l.clock.Now().UnixNano()
+
+	var (
+		allowed    bool
+		retryAfter time.Duration
+	)
+	l.c.Compute(k, func(tat int64, _ bool) (int64, cache.ComputeOp) {
+		tat = max(tat, now) + l.every
+		if allowAt := tat - l.limit; now < allowAt {
+			retryAfter = time.Duration(allowAt - now)
+			return tat, cache.ComputeCancel
+		}
+		allowed = true
+		return tat, cache.ComputeSet
+	})
+	return allowed, retryAfter
+}
+
+// Close releases the resources of the limiter. See cache.Cache.Close.
+func (l *GCRA[K]) Close() {
+	l.c.Close()
+}
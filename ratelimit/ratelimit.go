@@ -0,0 +1,49 @@
+// Package ratelimit provides rate limiters keyed by arbitrary comparable keys,
+// such as user IPs or API tokens.
+//
+// The state of each key is kept in a cache.Cache, and expires once the key
+// has been idle long enough for the state to be the same as a new key's.
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/phenpessoa/gutils/cache"
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// Limiter decides whether the events of a key may happen.
+//
+// A Limiter must be safe for concurrent use.
+type Limiter[K comparable] interface {
+	// Allow reports whether an event of k may happen now. If it may not,
+	// Allow also returns how long until it may.
+	//
+	// Events that are not allowed do not count towards the limit.
+	Allow(k K) (ok bool, retryAfter time.Duration)
+}
+
+// Option configures a Limiter.
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock makes the limiter tell the time with clk instead of the system
+// clock, which is mostly useful to test it with a fake clock.
+func WithClock(clk clock.Clock) Option {
+	return func(o *options) {
+		o.clock = clk
+	}
+}
+
+// newCache returns the cache holding the states of the keys of a limiter,
+// which expire after being idle for ttl.
+func newCache[K comparable, V any](ttl time.Duration, opts []Option) (*cache.Cache[K, V], clock.Clock) {
+	o := options{clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return cache.New(ttl, cache.WithClock[K, V](o.clock)), o.clock
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// testLimiter is a Limiter whose idle keys can be collected.
+type testLimiter interface {
+	Limiter[string]
+	Close()
+	gc() int
+}
+
+func (l *TokenBucket[K]) gc() int      { l.c.TickGC(); return l.c.Len() }
+func (l *SlidingWindowLog[K]) gc() int { l.c.TickGC(); return l.c.Len() }
+func (l *GCRA[K]) gc() int             { l.c.TickGC(); return l.c.Len() }
+
+func TestLimiters(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		new   func(clk clock.Clock) testLimiter
+		retry time.Duration
+		// refill is how many events are allowed after retry passes.
+		refill int
+	}{
+		{
+			"token bucket",
+			func(clk clock.Clock) testLimiter {
+				return NewTokenBucket[string](time.Second, 3, WithClock(clk))
+			},
+			time.Second,
+			1,
+		},
+		{
+			"sliding window log",
+			func(clk clock.Clock) testLimiter {
+				return NewSlidingWindowLog[string](3, 3*time.Second, WithClock(clk))
+			},
+			3 * time.Second,
+			3,
+		},
+		{
+			"gcra",
+			func(clk clock.Clock) testLimiter {
+				return NewGCRA[string](time.Second, 3, WithClock(clk))
+			},
+			time.Second,
+			1,
+		},
+	} {
+		clk := clock.NewFake(time.Now())
+		l := tc.new(clk)
+		if err := testLimit(l, clk, tc.retry, tc.refill); err != nil {
+			t.Errorf("\ntest '%s' failed\nerr: %v", tc.name, err)
+		}
+		l.Close()
+	}
+}
+
+func testLimit(l testLimiter, clk *clock.Fake, retry time.Duration, refill int) error {
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("foo"); !ok {
+			return fmt.Errorf("event %d of the burst was not allowed", i)
+		}
+	}
+
+	if ok, after := l.Allow("foo"); ok || after != retry {
+		return fmt.Errorf("expected event to be denied for %v but got %t, %v instead", retry, ok, after)
+	}
+
+	if ok, _ := l.Allow("bar"); !ok {
+		return fmt.Errorf("event of another key was not allowed")
+	}
+
+	clk.Advance(retry - time.Millisecond)
+	if ok, _ := l.Allow("foo"); ok {
+		return fmt.Errorf("event was allowed before its retry time")
+	}
+
+	clk.Advance(time.Millisecond)
+	for i := 0; i < refill; i++ {
+		if ok, _ := l.Allow("foo"); !ok {
+			return fmt.Errorf("event %d was not allowed after its retry time", i)
+		}
+	}
+
+	if ok, _ := l.Allow("foo"); ok {
+		return fmt.Errorf("event was allowed over the limit")
+	}
+
+	if n := l.gc(); n != 2 {
+		return fmt.Errorf("expected 2 keys but got %d instead", n)
+	}
+
+	clk.Advance(3*time.Second + time.Millisecond)
+	if n := l.gc(); n != 0 {
+		return fmt.Errorf("idle keys were not deleted, %d keys left", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("foo"); !ok {
+			return fmt.Errorf("event %d of the burst was not allowed after idling", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/phenpessoa/gutils/cache"
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// NewSlidingWindowLog returns a SlidingWindowLog allowing limit events of each
+// key in any window of time. It panics if limit or window are not positive.
+func NewSlidingWindowLog[K comparable](limit int, window time.Duration, opts ...Option) *SlidingWindowLog[K] {
+	if limit <= 0 || window <= 0 {
+		panic("ratelimit: non-positive limit or window for NewSlidingWindowLog")
+	}
+
+	// A log idle for this long only holds events out of the window.
+	c, clk := newCache[K, []int64](window, opts)
+	return &SlidingWindowLog[K]{c, clk, limit, int64(window)}
+}
+
+// SlidingWindowLog is a Limiter logging the time of the events of each key, and
+// allowing an event only if less than limit events were logged in the window
+// before it.
+//
+// It is the most precise of the limiters, but keeps up to limit timestamps for
+// each key.
+type SlidingWindowLog[K comparable] struct {
+	c      *cache.Cache[K, []int64]
+	clock  clock.Clock
+	limit  int
+	window int64
+}
+
+// Allow implements Limiter.
+func (l *SlidingWindowLog[K]) Allow(k K) (bool, time.Duration) {
+	now := l.clock.Now().UnixNano()
+
+	var (
+		allowed    bool
+		retryAfter time.Duration
+	)
+	l.c.Compute(k, func(log []int64, _ bool) ([]int64, cache.ComputeOp) {
+		// Drop the events out of the window.
+		i := 0
+		for i < len(log) && log[i] <= now-l.window {
+			i++
+		}
+		log = log[i:]
+
+		if len(log) >= l.limit {
+			retryAfter = time.Duration(log[0] + l.window - now)
+			return log, cache.ComputeCancel
+		}
+
+		if len(log) == cap(log) {
+			// Grow the log into a new array, instead of letting append
+			// keep the dropped events in the old one.
+			log = append(make([]int64, 0, min(l.limit, 2*len(log)+1)), log...)
+		}
+		allowed = true
+		return append(log, now), cache.ComputeSet
+	})
+	return allowed, retryAfter
+}
+
+// Close releases the resources of the limiter. See cache.Cache.Close.
+func (l *SlidingWindowLog[K]) Close() {
+	l.c.Close()
+}
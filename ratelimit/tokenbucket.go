@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/phenpessoa/gutils/cache"
+	"github.com/phenpessoa/gutils/cache/clock"
+)
+
+// NewTokenBucket returns a TokenBucket adding a token to the bucket of each key
+// every every, up to burst tokens. It panics if every or burst are not
+// positive.
+func NewTokenBucket[K comparable](every time.Duration, burst int, opts ...Option) *TokenBucket[K] {
+	if every <= 0 || burst <= 0 {
+		panic("ratelimit: non-positive rate or burst for NewTokenBucket")
+	}
+
+	// A bucket idle for this long is full, like a new one.
+	c, clk := newCache[K, tokenBucket](time.Duration(burst)*every, opts)
+	return &TokenBucket[K]{c, clk, every, float64(burst)}
+}
+
+// TokenBucket is a Limiter giving each key a bucket of tokens, refilled at a
+// constant rate. Each event takes a token, and is not allowed if the bucket is
+// empty, so bursts of events are allowed as long as the bucket has tokens.
+type TokenBucket[K comparable] struct {
+	c     *cache.Cache[K, tokenBucket]
+	clock clock.Clock
+	every time.Duration
+	burst float64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   int64
+}
+
+// Allow implements Limiter.
+func (l *TokenBucket[K]) Allow(k K) (bool, time.Duration) {
+	now := l.clock.Now().UnixNano()
+
+	var (
+		allowed    bool
+		retryAfter time.Duration
+	)
+	l.c.Compute(k, func(b tokenBucket, found bool) (tokenBucket, cache.ComputeOp) {
+		if !found {
+			b = tokenBucket{l.burst, now}
+		}
+		if now > b.last {
+			b.tokens = min(l.burst, b.tokens+float64(now-b.last)/float64(l.every))
+			b.last = now
+		}
+
+		if b.tokens < 1 {
+			retryAfter = time.Duration((1 - b.tokens) * float64(l.every))
+			return b, cache.ComputeCancel
+		}
+		b.tokens--
+		allowed = true
+		return b, cache.ComputeSet
+	})
+	return allowed, retryAfter
+}
+
+// Close releases the resources of the limiter. See cache.Cache.Close.
+func (l *TokenBucket[K]) Close() {
+	l.c.Close()
+}